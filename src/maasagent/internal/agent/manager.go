@@ -0,0 +1,210 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go.temporal.io/sdk/activity"
+
+	"maas.io/core/src/maasagent/internal/workflow/worker"
+)
+
+// ErrUnknownService is returned for any ServiceManager operation naming a
+// service that was never registered.
+type ErrUnknownService string
+
+func (e ErrUnknownService) Error() string {
+	return fmt.Sprintf("agent: unknown service %q", string(e))
+}
+
+// SetServiceEnabledParam is the payload for the "set-service-enabled"
+// activity ServiceManager registers on the pool's main worker, letting
+// the region controller enable/disable a service at runtime without
+// restarting the agent.
+type SetServiceEnabledParam struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// ServiceManager owns the agent's WorkerPool and every registered
+// Service, dispatching configuration to them and honoring independent
+// enable/disable state per service (set via WithEnabledServices/
+// WithDisabledServices at startup, or SetEnabled/the
+// "set-service-enabled" activity at runtime).
+type ServiceManager struct {
+	pool *worker.WorkerPool
+
+	mu       sync.Mutex
+	services map[string]Service
+	enabled  map[string]bool
+}
+
+// ServiceManagerOption configures a ServiceManager at construction time.
+type ServiceManagerOption func(*ServiceManager)
+
+// WithEnabledServices restricts which registered services start enabled,
+// e.g. from a --enable=dhcp,deploy flag. Any service not named here
+// starts disabled. Mutually exclusive with WithDisabledServices.
+func WithEnabledServices(names ...string) ServiceManagerOption {
+	return func(m *ServiceManager) {
+		for name := range m.enabled {
+			m.enabled[name] = false
+		}
+
+		for _, name := range names {
+			m.enabled[name] = true
+		}
+	}
+}
+
+// WithDisabledServices starts every registered service enabled except
+// those named here, e.g. from a --disable=netmon flag. Mutually
+// exclusive with WithEnabledServices.
+func WithDisabledServices(names ...string) ServiceManagerOption {
+	return func(m *ServiceManager) {
+		for _, name := range names {
+			m.enabled[name] = false
+		}
+	}
+}
+
+// NewServiceManager tracks services for configuration dispatch and
+// enable/disable bookkeeping. Each service's ConfigurationWorkflows/
+// ConfigurationActivities must still be wired onto pool at construction
+// time via worker.WithConfigurator(svc), same as before this package
+// existed; ServiceManager only adds the layer on top of that for
+// Configure/Health/Stop and per-service enable flags.
+//
+// All services start enabled unless overridden by WithEnabledServices/
+// WithDisabledServices.
+func NewServiceManager(pool *worker.WorkerPool, services []Service, options ...ServiceManagerOption) *ServiceManager {
+	m := &ServiceManager{
+		pool:     pool,
+		services: make(map[string]Service, len(services)),
+		enabled:  make(map[string]bool, len(services)),
+	}
+
+	for _, s := range services {
+		m.services[s.Name()] = s
+		m.enabled[s.Name()] = true
+	}
+
+	for _, opt := range options {
+		opt(m)
+	}
+
+	return m
+}
+
+// Configure applies cfg to the named service if it is currently enabled.
+// Configuring a disabled service is a no-op: cfg is discarded, and
+// re-enabling the service later via SetEnabled does not apply it.
+func (m *ServiceManager) Configure(ctx context.Context, name string, cfg json.RawMessage) error {
+	m.mu.Lock()
+	svc, ok := m.services[name]
+	enabled := m.enabled[name]
+	m.mu.Unlock()
+
+	if !ok {
+		return ErrUnknownService(name)
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	return svc.Configure(ctx, cfg)
+}
+
+// SetEnabled enables or disables the named service at runtime. Disabling
+// calls Stop on the service; enabling is the caller's responsibility to
+// follow with a Configure call carrying whatever configuration the
+// service needs to actually start doing work again.
+func (m *ServiceManager) SetEnabled(ctx context.Context, name string, enabled bool) error {
+	m.mu.Lock()
+	svc, ok := m.services[name]
+	m.mu.Unlock()
+
+	if !ok {
+		return ErrUnknownService(name)
+	}
+
+	if !enabled {
+		if err := svc.Stop(ctx); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.enabled[name] = enabled
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Health reports the health of every enabled, registered service. A
+// disabled service is not checked and never contributes an error.
+func (m *ServiceManager) Health(ctx context.Context) map[string]error {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.services))
+
+	for name, enabled := range m.enabled {
+		if enabled {
+			names = append(names, name)
+		}
+	}
+	m.mu.Unlock()
+
+	result := make(map[string]error, len(names))
+
+	for _, name := range names {
+		m.mu.Lock()
+		svc, ok := m.services[name]
+		m.mu.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		result[name] = svc.Health(ctx)
+	}
+
+	return result
+}
+
+// setServiceEnabled is the activity implementation behind
+// "set-service-enabled", registered on the main worker so the region
+// controller can flip a service's enabled state without restarting the
+// agent.
+func (m *ServiceManager) setServiceEnabled(ctx context.Context, param SetServiceEnabledParam) error {
+	log := activity.GetLogger(ctx)
+	log.Debug("updating service enabled state", "name", param.Name, "enabled", param.Enabled)
+
+	return m.SetEnabled(ctx, param.Name, param.Enabled)
+}
+
+// RegisterActivities wires ServiceManager's own "set-service-enabled"
+// activity onto pool's main worker. Each Service's own
+// ConfigurationWorkflows/ConfigurationActivities are not registered
+// here; they are wired onto the pool via worker.WithConfigurator.
+func (m *ServiceManager) RegisterActivities() {
+	m.pool.RegisterActivityWithOptions(m.setServiceEnabled, activity.RegisterOptions{
+		Name: "set-service-enabled",
+	})
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package agent defines the common lifecycle surface every MAAS Agent
+// subsystem (DHCP, Deploy, ...) implements, and a ServiceManager that
+// dispatches configuration to them and honors independent enable/disable
+// flags for each.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Service is the lifecycle surface every agent subsystem exposes to
+// ServiceManager. It replaces the ad-hoc Configure/ConfigurationWorkflows/
+// ConfigurationActivities/ConfiguratorName shapes each subsystem used to
+// expose independently (e.g. DeployService.Configure took a systemID
+// string, DHCPService.configure took a DHCPServiceConfigParam).
+type Service interface {
+	// Name identifies the service, e.g. "dhcp" or "deploy". Used as the
+	// key for enable/disable flags and the ServiceManager's internal
+	// bookkeeping.
+	Name() string
+
+	// ConfigurationWorkflows returns workflows to be registered on the
+	// WorkerPool's main worker so the region controller can push
+	// configuration via Temporal.
+	ConfigurationWorkflows() map[string]interface{}
+
+	// ConfigurationActivities returns activities to be registered
+	// alongside ConfigurationWorkflows.
+	ConfigurationActivities() map[string]interface{}
+
+	// Configure applies cfg immediately, outside of any Temporal
+	// workflow. ServiceManager.Configure calls this while the service
+	// is enabled; ServiceManager.SetEnabled does not call it when
+	// re-enabling the service.
+	Configure(ctx context.Context, cfg json.RawMessage) error
+
+	// Health reports whether the service is currently operating
+	// correctly.
+	Health(ctx context.Context) error
+
+	// Stop tears down any resources the service holds (running
+	// daemons, registered workers, open connections).
+	Stop(ctx context.Context) error
+}
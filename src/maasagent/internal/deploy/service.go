@@ -15,6 +15,8 @@
 package deploy
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -39,11 +41,60 @@ func NewDeployService(systemID string, pool *worker.WorkerPool) *DeployService {
 	}
 }
 
-func (s *DeployService) ConfiguratorName() string {
-	return "configure-deploy-service"
+// Name implements agent.Service.
+func (s *DeployService) Name() string {
+	return "deploy"
 }
 
-func (s *DeployService) Configure(ctx tworkflow.Context, systemID string) error {
+// DeployServiceConfigParam is the payload Configure expects, carrying
+// the system ID whose rack-controller VLANs should be (re)watched.
+type DeployServiceConfigParam struct {
+	SystemID string `json:"system_id"`
+}
+
+// Configure implements agent.Service, applying cfg immediately outside
+// of any Temporal workflow. It only records cfg's system ID on the
+// service. The per-VLAN worker provisioning is driven by the Temporal
+// workflow registered via ConfigurationWorkflows, which takes the
+// system ID as its own argument and does not read this one.
+func (s *DeployService) Configure(ctx context.Context, cfg json.RawMessage) error {
+	var param DeployServiceConfigParam
+
+	if err := json.Unmarshal(cfg, &param); err != nil {
+		return fmt.Errorf("unmarshalling deploy service config: %w", err)
+	}
+
+	s.systemID = param.SystemID
+
+	return nil
+}
+
+// Health implements agent.Service.
+func (s *DeployService) Health(ctx context.Context) error {
+	return nil
+}
+
+// Stop implements agent.Service, removing every worker this service
+// registered for its VLAN-specific deploy task queues.
+func (s *DeployService) Stop(ctx context.Context) error {
+	s.pool.RemoveWorkers(deployServiceWorkerPoolGroup)
+	return nil
+}
+
+// ConfigurationWorkflows implements agent.Service.
+func (s *DeployService) ConfigurationWorkflows() map[string]interface{} {
+	return map[string]interface{}{"configure-deploy-service": s.configureWorkflow}
+}
+
+// ConfigurationActivities implements agent.Service.
+func (s *DeployService) ConfigurationActivities() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// configureWorkflow is the Temporal workflow the region controller
+// drives to (re)provision this agent's per-VLAN deploy workers whenever
+// its rack controller assignment changes.
+func (s *DeployService) configureWorkflow(ctx tworkflow.Context, systemID string) error {
 	s.pool.RemoveWorkers(deployServiceWorkerPoolGroup)
 
 	type getAgentVLANsParam struct {
@@ -55,6 +55,7 @@ var (
 	ErrIPAllocationConflict        = errors.New("one or more IPs proposed for allocation were already allocated")
 	ErrInsufficientUserPermissions = errors.New("requesting user does not have permissions for this operation")
 	ErrInvalidStorageConfig        = errors.New("the storage configuration is invalid with the given params")
+	ErrDeployCancelled             = errors.New("deployment was cancelled")
 )
 
 type AllocateIPsInput struct {
@@ -77,6 +78,10 @@ type ClaimIPsInput struct {
 	MACs     []string      `json:"macs"`
 }
 
+type ReleaseIPsInput struct {
+	SystemID string `json:"system_id"`
+}
+
 type DeployInput struct {
 	SystemID         string `json:"system_id"`
 	Queue            string `json:"queue"`
@@ -158,19 +163,13 @@ type NodeStatusInput struct {
 }
 
 func checkForBootInterfaceLease(ctx tworkflow.Context, systemID string) error {
-	var (
-		leaseSig *workflow.LeaseSignal
-		err      error
+	_, err := workflow.HandleSignalPredicate[workflow.LeaseSignal](
+		ctx,
+		fmt.Sprintf("leases:%s", systemID),
+		func(sig workflow.LeaseSignal) bool { return sig.IsBootInterface },
 	)
 
-	for leaseSig == nil || !leaseSig.IsBootInterface {
-		leaseSig, err = workflow.HandleSignal[workflow.LeaseSignal](ctx, fmt.Sprintf("leases:%s", systemID))
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return err
 }
 
 func checkAllBootAssets(ctx tworkflow.Context, systemID string, bootAssets []string) error {
@@ -235,8 +234,24 @@ func AllocateIPs(ctx tworkflow.Context, input AllocateIPsInput) error {
 		}
 	}
 
-	err = tworkflow.ExecuteActivity(ctx, "claim-ips", claimIPs).Get(ctx, nil)
-	if err != nil {
+	// No saga here: a single-step saga can never run its own
+	// compensator (Saga.Execute only compensates steps preceding the
+	// one that failed). That rules out Deploy's release-ips
+	// compensator too: Deploy's saga step invokes this workflow as a
+	// single step, so a failure here is the failing step, and a
+	// saga never compensates the step that failed, only the ones
+	// before it. If claim-ips claims some IPs and then errors, nothing
+	// upstream will ever release them, so this workflow must release
+	// them itself before returning the error.
+	if err := tworkflow.ExecuteActivity(ctx, "claim-ips", claimIPs).Get(ctx, nil); err != nil {
+		disconnectedCtx, cancel := tworkflow.NewDisconnectedContext(ctx)
+		defer cancel()
+
+		release := ReleaseIPsInput{SystemID: input.SystemID}
+		if releaseErr := tworkflow.ExecuteActivity(disconnectedCtx, "release-ips", release).Get(disconnectedCtx, nil); releaseErr != nil {
+			tworkflow.GetLogger(ctx).Error("releasing partially claimed IPs after claim-ips failure", "error", releaseErr)
+		}
+
 		return err
 	}
 
@@ -292,9 +307,21 @@ func DeployEphemeralOS(ctx tworkflow.Context, input DeployEphemeralOSInput) erro
 		return err
 	}
 
-	_, err = workflow.HandleSignal[workflow.CurtinFinishedSignal](ctx, fmt.Sprintf("curtin-finished:%s", input.SystemID))
-	if err != nil {
-		return err
+	var cancelled bool
+
+	workflow.SelectSignals(ctx,
+		workflow.SignalCase[workflow.CurtinFinishedSignal]{
+			Channel: fmt.Sprintf("curtin-finished:%s", input.SystemID),
+			Handler: func(workflow.CurtinFinishedSignal) {},
+		},
+		workflow.SignalCase[workflow.DeployCancelledSignal]{
+			Channel: fmt.Sprintf("deploy-cancelled:%s", input.SystemID),
+			Handler: func(workflow.DeployCancelledSignal) { cancelled = true },
+		},
+	)
+
+	if cancelled {
+		return ErrDeployCancelled
 	}
 
 	return nil
@@ -422,58 +449,105 @@ func Deploy(ctx tworkflow.Context, input DeployInput) error {
 
 	var deployParams SetDeployParamsResult
 
-	setDeployParamsCtx := tworkflow.WithActivityOptions(ctx, tworkflow.ActivityOptions{
-		StartToCloseTimeout: 10 * time.Second,
-	})
+	saga := workflow.NewSaga()
+
+	saga.AddStep(
+		func(ctx tworkflow.Context) error {
+			setDeployParamsCtx := tworkflow.WithActivityOptions(ctx, tworkflow.ActivityOptions{
+				StartToCloseTimeout: 10 * time.Second,
+			})
+
+			err := tworkflow.ExecuteActivity(
+				setDeployParamsCtx,
+				"set-deploy-params",
+				input,
+			).Get(setDeployParamsCtx, &deployParams)
+			if err != nil {
+				return err
+			}
 
-	err = tworkflow.ExecuteActivity(
-		setDeployParamsCtx,
-		"set-deploy-params",
-		input,
-	).Get(setDeployParamsCtx, &deployParams)
-	if err != nil {
-		return err
-	}
+			if deployParams.Status != NodeStatusReady && deployParams.Status != NodeStatusAllocated {
+				return ErrInvalidNodeStatus
+			}
 
-	if deployParams.Status != NodeStatusReady && deployParams.Status != NodeStatusAllocated {
-		return failDeployment(input.SystemID, ErrInvalidNodeStatus)
-	}
+			return nil
+		},
+		func(ctx tworkflow.Context) error {
+			return tworkflow.ExecuteActivity(ctx, "update-node-status", NodeStatusInput{
+				SystemID: input.SystemID,
+				Status:   deployParams.PreviousStatus,
+			}).Get(ctx, nil)
+		},
+	)
 
-	err = tworkflow.ExecuteChildWorkflow(ctx, AllocateIPs, AllocateIPsInput{
-		SystemID: input.SystemID,
-	}).Get(ctx, nil)
-	if err != nil {
-		return err
-	}
+	saga.AddStep(
+		func(ctx tworkflow.Context) error {
+			return tworkflow.ExecuteChildWorkflow(ctx, AllocateIPs, AllocateIPsInput{
+				SystemID: input.SystemID,
+			}).Get(ctx, nil)
+		},
+		func(ctx tworkflow.Context) error {
+			return tworkflow.ExecuteActivity(ctx, "release-ips", ReleaseIPsInput{SystemID: input.SystemID}).Get(ctx, nil)
+		},
+	)
 
-	err = tworkflow.ExecuteChildWorkflow(ctx, DeployEphemeralOS, DeployEphemeralOSInput{
-		SystemID:     input.SystemID,
-		PowerParams:  powerParams,
-		DeployParams: deployParams,
-	}).Get(ctx, nil)
-	if err != nil {
-		return err
-	}
+	saga.AddStep(
+		func(ctx tworkflow.Context) error {
+			return tworkflow.ExecuteChildWorkflow(ctx, DeployEphemeralOS, DeployEphemeralOSInput{
+				SystemID:     input.SystemID,
+				PowerParams:  powerParams,
+				DeployParams: deployParams,
+			}).Get(ctx, nil)
+		},
+		nil,
+	)
 
 	if !input.EphemeralDeploy {
-		err = tworkflow.ExecuteActivity(
-			ctx,
-			"set-boot-order",
-			SetBootOrderInput{SystemID: input.SystemID, Netboot: false},
-		).Get(ctx, nil)
-		if err != nil {
-			return err
-		}
+		saga.AddStep(
+			func(ctx tworkflow.Context) error {
+				return tworkflow.ExecuteActivity(
+					ctx,
+					"set-boot-order",
+					SetBootOrderInput{SystemID: input.SystemID, Netboot: false},
+				).Get(ctx, nil)
+			},
+			func(ctx tworkflow.Context) error {
+				return tworkflow.ExecuteActivity(
+					ctx,
+					"set-boot-order",
+					SetBootOrderInput{SystemID: input.SystemID, Netboot: deployParams.Netboot},
+				).Get(ctx, nil)
+			},
+		)
+
+		saga.AddStep(
+			func(ctx tworkflow.Context) error {
+				return tworkflow.ExecuteChildWorkflow(ctx, DeployInstalledOS, DeployInstalledOSInput{
+					SystemID:     input.SystemID,
+					PowerParams:  powerParams,
+					DeployParams: deployParams,
+				}).Get(ctx, nil)
+			},
+			nil,
+		)
+	}
 
-		err = tworkflow.ExecuteChildWorkflow(ctx, DeployInstalledOS, DeployInstalledOSInput{
-			SystemID:     input.SystemID,
-			PowerParams:  powerParams,
-			DeployParams: deployParams,
+	if err := saga.Execute(ctx); err != nil {
+		failErr := failDeployment(input.SystemID, err)
+
+		statusErr := tworkflow.ExecuteActivity(ctx, "update-node-status", NodeStatusInput{
+			SystemID: input.SystemID,
+			Status:   NodeStatusFailedDeployment,
 		}).Get(ctx, nil)
-		if err != nil {
-			return err
+		if statusErr != nil {
+			return failDeployment(input.SystemID, failErr, statusErr.Error())
 		}
+
+		return failErr
 	}
 
-	return tworkflow.ExecuteActivity(ctx, "update-node-status", NodeStatusInput{Status: NodeStatusDeployed}).Get(ctx, nil)
+	return tworkflow.ExecuteActivity(ctx, "update-node-status", NodeStatusInput{
+		SystemID: input.SystemID,
+		Status:   NodeStatusDeployed,
+	}).Get(ctx, nil)
 }
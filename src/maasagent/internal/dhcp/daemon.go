@@ -0,0 +1,169 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package dhcp
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+)
+
+// daemonSupervisor owns the lifecycle of a single isc-dhcp-server process
+// (dhcpd for IPv4 or dhcpd6 for IPv6) for a rendered configuration file.
+// It is intentionally dumb: it does not know how to render or fetch
+// configuration, only how to validate, (re)start, and stop the daemon
+// that serves it.
+type daemonSupervisor struct {
+	name       string // process name, e.g. "dhcpd" or "dhcpd6"
+	ipv6       bool
+	confPath   string
+	leasePath  string
+	pidPath    string
+	interfaces []string
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+}
+
+func newDaemonSupervisor(name string, ipv6 bool, confPath, leasePath, pidPath string, interfaces []string) *daemonSupervisor {
+	return &daemonSupervisor{
+		name:       name,
+		ipv6:       ipv6,
+		confPath:   confPath,
+		leasePath:  leasePath,
+		pidPath:    pidPath,
+		interfaces: interfaces,
+	}
+}
+
+func (d *daemonSupervisor) args(extra ...string) []string {
+	args := []string{"-cf", d.confPath, "-lf", d.leasePath, "-pf", d.pidPath}
+	if d.ipv6 {
+		args = append(args, "-6")
+	}
+
+	args = append(args, extra...)
+
+	return append(args, d.interfaces...)
+}
+
+// validate runs `dhcpd -t` against the currently rendered configuration,
+// returning a descriptive error including dhcpd's own stderr output if the
+// config is rejected.
+func (d *daemonSupervisor) validate(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, d.name, d.args("-t")...)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s -t: %w: %s", d.name, err, out)
+	}
+
+	return nil
+}
+
+// start launches the daemon in the foreground under our supervision (-f),
+// so its exit can be observed and reported through DHCPService.Error.
+// If the daemon is already running it is reloaded instead.
+func (d *daemonSupervisor) start(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cmd != nil && d.cmd.Process != nil {
+		return d.reloadLocked(ctx)
+	}
+
+	if err := d.validate(ctx); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(d.name, d.args("-f")...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", d.name, err)
+	}
+
+	d.cmd = cmd
+
+	return nil
+}
+
+// reload picks up the rendered configuration by stopping the running
+// daemon and starting a new process against it. isc-dhcpd installs no
+// SIGHUP handler — the default disposition terminates the process —
+// so a signal-based reload isn't available; this is a full restart,
+// which does mean existing leases are (briefly) not being served
+// until the new process comes up. Callers should validate the new
+// configuration before calling reload.
+func (d *daemonSupervisor) reload(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.reloadLocked(ctx)
+}
+
+func (d *daemonSupervisor) reloadLocked(ctx context.Context) error {
+	if d.cmd == nil || d.cmd.Process == nil {
+		return fmt.Errorf("%s is not running", d.name)
+	}
+
+	if err := d.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("stopping %s for reload: %w", d.name, err)
+	}
+
+	_ = d.cmd.Wait()
+
+	cmd := exec.Command(d.name, d.args("-f")...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("restarting %s: %w", d.name, err)
+	}
+
+	d.cmd = cmd
+
+	return nil
+}
+
+// stop terminates the daemon and waits for it to exit.
+func (d *daemonSupervisor) stop() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cmd == nil || d.cmd.Process == nil {
+		return nil
+	}
+
+	if err := d.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("stopping %s: %w", d.name, err)
+	}
+
+	_ = d.cmd.Wait()
+	d.cmd = nil
+
+	return nil
+}
+
+// wait blocks until the supervised process exits, returning its exit
+// error (nil on a clean exit). Intended to be run in its own goroutine so
+// an unexpected daemon crash can be surfaced on DHCPService's fatal channel.
+func (d *daemonSupervisor) wait() error {
+	d.mu.Lock()
+	cmd := d.cmd
+	d.mu.Unlock()
+
+	if cmd == nil {
+		return nil
+	}
+
+	return cmd.Wait()
+}
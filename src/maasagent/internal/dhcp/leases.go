@@ -0,0 +1,170 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package dhcp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	tclient "go.temporal.io/sdk/client"
+
+	"maas.io/core/src/maasagent/internal/workflow"
+)
+
+// leasePollInterval is how often leaseTailer checks the leases file for
+// new bytes appended by dhcpd.
+const leasePollInterval = time.Second
+
+// leaseStanzaStart matches the opening line of a "lease <ip> { ... }"
+// stanza in dhcpd.leases.
+var leaseStanzaStart = regexp.MustCompile(`^lease (\S+) \{`)
+
+// leaseHardware matches the "hardware ethernet <mac>;" line inside a
+// lease stanza.
+var leaseHardware = regexp.MustCompile(`^\s*hardware ethernet (\S+);`)
+
+// leaseTailer tails an isc-dhcp-server leases file and emits a
+// workflow.LeaseSignal into the "leases:<system_id>" Temporal signal
+// stream for every new lease stanza it observes, which is what
+// checkForBootInterfaceLease (internal/deploy/workflow.go) is waiting on.
+type leaseTailer struct {
+	path     string
+	temporal tclient.Client
+	resolver systemIDResolver
+	// onError, if set, is called with non-fatal errors encountered while
+	// signalling individual leases (e.g. a workflow that already closed).
+	onError func(error)
+}
+
+// systemIDResolver maps a MAC address observed in a lease to the MAAS
+// system ID of the node it belongs to, so the lease signal can be routed
+// to that node's workflow.
+type systemIDResolver interface {
+	// SystemIDForMAC resolves a leased MAC address to the MAAS system ID
+	// of the node it belongs to, and whether that MAC is the node's
+	// configured boot interface.
+	SystemIDForMAC(ctx context.Context, mac string) (systemID string, isBootInterface bool, err error)
+}
+
+func newLeaseTailer(path string, temporal tclient.Client, resolver systemIDResolver) *leaseTailer {
+	return &leaseTailer{
+		path:     path,
+		temporal: temporal,
+		resolver: resolver,
+	}
+}
+
+// run tails the leases file until ctx is cancelled. It returns an error
+// only if the leases file cannot be opened or seeked to its end; read
+// errors after that are treated as no new data, and the file being
+// removed or replaced is not detected.
+func (t *leaseTailer) run(ctx context.Context) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("opening leases file %s: %w", t.path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(0, os.SEEK_END); err != nil {
+		return fmt.Errorf("seeking leases file %s: %w", t.path, err)
+	}
+
+	reader := bufio.NewReader(f)
+	ticker := time.NewTicker(leasePollInterval)
+
+	defer ticker.Stop()
+
+	var stanza []string
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			for {
+				line, err := reader.ReadString('\n')
+				if line != "" {
+					stanza = append(stanza, line)
+
+					if trimmed := trimNewline(line); trimmed == "}" {
+						t.handleStanza(ctx, stanza)
+						stanza = nil
+					}
+				}
+
+				if err != nil {
+					// No more data yet; wait for next tick.
+					break
+				}
+			}
+		}
+	}
+}
+
+func trimNewline(s string) string {
+	for len(s) > 0 && (s[len(s)-1] == '\n' || s[len(s)-1] == '\r') {
+		s = s[:len(s)-1]
+	}
+
+	return s
+}
+
+// handleStanza parses a complete "lease { ... }" stanza and, if it
+// describes a new lease for a known node, signals that node's workflow.
+func (t *leaseTailer) handleStanza(ctx context.Context, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	m := leaseStanzaStart.FindStringSubmatch(lines[0])
+	if m == nil {
+		return
+	}
+
+	ip := m[1]
+
+	var mac string
+
+	for _, line := range lines[1:] {
+		if m := leaseHardware.FindStringSubmatch(line); m != nil {
+			mac = m[1]
+			break
+		}
+	}
+
+	if mac == "" {
+		return
+	}
+
+	systemID, isBootInterface, err := t.resolver.SystemIDForMAC(ctx, mac)
+	if err != nil || systemID == "" {
+		return
+	}
+
+	sig := workflow.LeaseSignal{
+		SystemID:        systemID,
+		IP:              ip,
+		MAC:             mac,
+		IsBootInterface: isBootInterface,
+	}
+
+	if err := t.temporal.SignalWorkflow(ctx, systemID, "", fmt.Sprintf("leases:%s", systemID), sig); err != nil && t.onError != nil {
+		t.onError(fmt.Errorf("signalling lease for %s: %w", systemID, err))
+	}
+}
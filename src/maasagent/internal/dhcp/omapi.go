@@ -0,0 +1,422 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package dhcp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5" //nolint:gosec // required by the ISC DHCP OMAPI wire protocol
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// ErrOMAPIObjectNotFound is returned when the server has no object
+// matching the lookup key we sent it.
+var ErrOMAPIObjectNotFound = errors.New("omapi: object not found")
+
+// OMAPI opcodes, from the ISC dhcpd omapip protocol (omapip/omapip.h's
+// OMAPI_OP_* defines).
+const (
+	omapiOpOpen    = 1
+	omapiOpRefresh = 2
+	omapiOpUpdate  = 3
+	omapiOpNotify  = 4
+	omapiOpStatus  = 5
+	omapiOpDelete  = 6
+)
+
+// omapiKeyName is the name of the key dhcpd.conf declares (the
+// "key maas-key { ... }" statement rendered by renderConfig) and the
+// name login registers the connection's authenticator object under.
+const omapiKeyName = "maas-key"
+
+// omapiHeaderLen is the size in bytes of the fixed OMAPI message
+// header: authid, authlen, op, handle, id, rid, each a big-endian
+// uint32.
+const omapiHeaderLen = 24
+
+// omapiClient is a minimal client for the ISC DHCP OMAPI protocol, just
+// enough to add/delete "host" objects so DHCPService.update can push
+// incremental reservation changes without a full dhcpd reload.
+//
+// See the dhcpd man page section "OMAPI", and omapip/protocol.c in the
+// ISC DHCP source, for the wire format this implements: a 24-byte
+// header, a sequence of length-prefixed name/value pairs (the
+// "message") terminated by a zero-length name, an HMAC-MD5 signature
+// over the header and message (present once the connection has logged
+// in with a key), and a second sequence of name/value pairs (the
+// "object").
+type omapiClient struct {
+	addr string
+	key  []byte
+}
+
+func newOMAPIClient(host string, port int, key []byte) *omapiClient {
+	return &omapiClient{
+		addr: net.JoinHostPort(host, fmt.Sprintf("%d", port)),
+		key:  key,
+	}
+}
+
+// omapiPair is one length-prefixed name/value entry in an OMAPI
+// message or object section.
+type omapiPair struct {
+	name  string
+	value []byte
+}
+
+// omapiResponse is a decoded OMAPI reply: a header plus its message
+// and object sections.
+type omapiResponse struct {
+	op     uint32
+	handle uint32
+	id     uint32
+	rid    uint32
+
+	message []omapiPair
+	object  []omapiPair
+}
+
+// omapiConn is a single OMAPI session. authID is the handle of the
+// "authenticator" object login opened, or 0 if the client was
+// constructed without a key; nextID generates this connection's
+// request transaction IDs.
+type omapiConn struct {
+	net.Conn
+
+	key    []byte
+	authID uint32
+	nextID uint32
+}
+
+func (c *omapiClient) dial(ctx context.Context) (*omapiConn, error) {
+	d := net.Dialer{}
+
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("omapi: dial %s: %w", c.addr, err)
+	}
+
+	oc := &omapiConn{Conn: conn, key: c.key, nextID: 1}
+
+	if err := oc.handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if len(c.key) > 0 {
+		if err := oc.login(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return oc, nil
+}
+
+// handshake exchanges the protocol version and header size used by
+// both ends of the connection, as required before any message can be
+// sent.
+func (c *omapiConn) handshake() error {
+	var out [8]byte
+
+	binary.BigEndian.PutUint32(out[0:4], 100)
+	binary.BigEndian.PutUint32(out[4:8], omapiHeaderLen)
+
+	if _, err := c.Write(out[:]); err != nil {
+		return fmt.Errorf("omapi: sending version: %w", err)
+	}
+
+	var in [8]byte
+
+	if _, err := io.ReadFull(c, in[:]); err != nil {
+		return fmt.Errorf("omapi: reading version: %w", err)
+	}
+
+	return nil
+}
+
+// login opens dhcpd's built-in "authenticator" object under the shared
+// key, so the handle it returns can be set as authid on every
+// subsequent message on this connection, and those messages signed
+// with the key.
+func (c *omapiConn) login() error {
+	message := []omapiPair{{name: "type", value: []byte("authenticator")}}
+	object := []omapiPair{
+		{name: "name", value: []byte(omapiKeyName)},
+		{name: "algorithm", value: []byte("hmac-md5.SIG-ALG.REG.INT")},
+		{name: "key", value: c.key},
+	}
+
+	// Unsigned: no authenticator handle exists yet to sign with.
+	msg := c.build(omapiOpOpen, 0, message, object, false)
+
+	if _, err := c.Write(msg); err != nil {
+		return fmt.Errorf("omapi: sending authenticator login: %w", err)
+	}
+
+	resp, err := c.read()
+	if err != nil {
+		return fmt.Errorf("omapi: reading authenticator login response: %w", err)
+	}
+
+	if resp.op != omapiOpUpdate || resp.handle == 0 {
+		return errors.New("omapi: server rejected authenticator login")
+	}
+
+	c.authID = resp.handle
+
+	return nil
+}
+
+func writeValue(buf []byte, name string, value []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(name)))
+	buf = append(buf, name...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(value)))
+	buf = append(buf, value...)
+
+	return buf
+}
+
+// encodePairs appends pairs to buf, each as a length-prefixed
+// name/value entry, followed by the zero-length name that terminates
+// the section.
+func encodePairs(buf []byte, pairs []omapiPair) []byte {
+	for _, p := range pairs {
+		buf = writeValue(buf, p.name, p.value)
+	}
+
+	return binary.BigEndian.AppendUint16(buf, 0)
+}
+
+// encodeUint32 encodes v as the 4-byte big-endian integer OMAPI
+// expects for integer-valued attributes (e.g. "create", "exclusive").
+func encodeUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+
+	return b
+}
+
+// sign computes the HMAC-MD5 signature of data using the shared OMAPI
+// key, as required when the "authenticator" object in use is of type
+// "hmac-md5".
+func sign(key, data []byte) []byte {
+	mac := hmac.New(md5.New, key)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
+
+// build encodes one OMAPI message: header, message section, signature
+// (when signed), and object section. The signature, when present,
+// covers only the header and message — not the object section that
+// follows it — matching dhcpd's omapi_protocol_send_message.
+func (c *omapiConn) build(op, handle uint32, message, object []omapiPair, signed bool) []byte {
+	id := c.nextID
+	c.nextID++
+
+	var authID, authLen uint32
+
+	if signed {
+		authID = c.authID
+		authLen = md5.Size
+	}
+
+	buf := make([]byte, 0, omapiHeaderLen)
+	buf = binary.BigEndian.AppendUint32(buf, authID)
+	buf = binary.BigEndian.AppendUint32(buf, authLen)
+	buf = binary.BigEndian.AppendUint32(buf, op)
+	buf = binary.BigEndian.AppendUint32(buf, handle)
+	buf = binary.BigEndian.AppendUint32(buf, id)
+	buf = binary.BigEndian.AppendUint32(buf, 0) // rid: unset on a request
+
+	buf = encodePairs(buf, message)
+
+	if signed {
+		buf = append(buf, sign(c.key, buf)...)
+	}
+
+	return encodePairs(buf, object)
+}
+
+// readPairs decodes a section of length-prefixed name/value pairs,
+// stopping at the zero-length name that terminates it.
+func readPairs(r io.Reader) ([]omapiPair, error) {
+	var pairs []omapiPair
+
+	for {
+		var nameLen uint16
+
+		if err := binary.Read(r, binary.BigEndian, &nameLen); err != nil {
+			return nil, err
+		}
+
+		if nameLen == 0 {
+			return pairs, nil
+		}
+
+		name := make([]byte, nameLen)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, err
+		}
+
+		var valueLen uint32
+
+		if err := binary.Read(r, binary.BigEndian, &valueLen); err != nil {
+			return nil, err
+		}
+
+		value := make([]byte, valueLen)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, omapiPair{name: string(name), value: value})
+	}
+}
+
+// read decodes one OMAPI response: header, message section, signature
+// (its bytes are consumed but not re-verified — we trust the
+// connection we just authenticated), and object section.
+func (c *omapiConn) read() (*omapiResponse, error) {
+	header := make([]byte, omapiHeaderLen)
+
+	if _, err := io.ReadFull(c, header); err != nil {
+		return nil, fmt.Errorf("omapi: reading header: %w", err)
+	}
+
+	authLen := binary.BigEndian.Uint32(header[4:8])
+
+	resp := &omapiResponse{
+		op:     binary.BigEndian.Uint32(header[8:12]),
+		handle: binary.BigEndian.Uint32(header[12:16]),
+		id:     binary.BigEndian.Uint32(header[16:20]),
+		rid:    binary.BigEndian.Uint32(header[20:24]),
+	}
+
+	message, err := readPairs(c)
+	if err != nil {
+		return nil, fmt.Errorf("omapi: reading message: %w", err)
+	}
+
+	resp.message = message
+
+	if authLen > 0 {
+		sig := make([]byte, authLen)
+		if _, err := io.ReadFull(c, sig); err != nil {
+			return nil, fmt.Errorf("omapi: reading signature: %w", err)
+		}
+	}
+
+	object, err := readPairs(c)
+	if err != nil {
+		return nil, fmt.Errorf("omapi: reading object: %w", err)
+	}
+
+	resp.object = object
+
+	return resp, nil
+}
+
+// query sends an OMAPI request (signed once the connection has logged
+// in) and returns its decoded response. An OMAPI_OP_STATUS reply is
+// reported as ErrOMAPIObjectNotFound, the only failure mode the
+// create/delete/lookup calls below need to distinguish.
+func (c *omapiConn) query(op, handle uint32, message, object []omapiPair) (*omapiResponse, error) {
+	if err := c.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return nil, fmt.Errorf("omapi: setting deadline: %w", err)
+	}
+
+	msg := c.build(op, handle, message, object, c.authID != 0)
+
+	if _, err := c.Write(msg); err != nil {
+		return nil, fmt.Errorf("omapi: writing message: %w", err)
+	}
+
+	resp, err := c.read()
+	if err != nil {
+		return nil, fmt.Errorf("omapi: reading response: %w", err)
+	}
+
+	if resp.op == omapiOpStatus {
+		return resp, ErrOMAPIObjectNotFound
+	}
+
+	return resp, nil
+}
+
+// AddHost registers a static host reservation via OMAPI rather than
+// requiring a full dhcpd.conf re-render and reload.
+func (c *omapiClient) AddHost(ctx context.Context, h Host) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	message := []omapiPair{
+		{name: "type", value: []byte("host")},
+		{name: "create", value: encodeUint32(1)},
+		{name: "exclusive", value: encodeUint32(1)},
+	}
+	object := []omapiPair{
+		{name: "hardware-address", value: parseMAC(h.MACAddress)},
+		{name: "hardware-type", value: encodeUint32(1)}, // HTYPE_ETHER
+		{name: "ip-address", value: net.ParseIP(h.IPAddress).To4()},
+		{name: "name", value: []byte(h.Hostname)},
+	}
+
+	_, err = conn.query(omapiOpOpen, 0, message, object)
+
+	return err
+}
+
+// RemoveHost deletes a static host reservation previously registered
+// via AddHost or rendered statically into dhcpd.conf. OMAPI has no
+// "delete by name" request, so this first looks the object up to
+// obtain its handle, then issues the delete against that handle.
+func (c *omapiClient) RemoveHost(ctx context.Context, h Host) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	lookup := []omapiPair{{name: "type", value: []byte("host")}}
+	key := []omapiPair{{name: "name", value: []byte(h.Hostname)}}
+
+	resp, err := conn.query(omapiOpOpen, 0, lookup, key)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.query(omapiOpDelete, resp.handle, nil, nil)
+
+	return err
+}
+
+func parseMAC(s string) []byte {
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		return nil
+	}
+
+	return mac
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package dhcp
+
+import (
+	"context"
+	"fmt"
+
+	"maas.io/core/src/maasagent/internal/apiclient"
+)
+
+// systemIDForMACPath is the region controller endpoint used to resolve a
+// leased MAC address to the owning node's system ID and whether it's that
+// node's configured boot interface.
+const systemIDForMACPath = "/api/2.0/dhcp/interfaces/%s/"
+
+type systemIDForMACResult struct {
+	SystemID        string `json:"system_id"`
+	IsBootInterface bool   `json:"is_boot_interface"`
+}
+
+// apiClientResolver implements systemIDResolver against the region
+// controller's REST API via apiclient.APIClient.
+type apiClientResolver struct {
+	client *apiclient.APIClient
+}
+
+func (r *apiClientResolver) SystemIDForMAC(ctx context.Context, mac string) (string, bool, error) {
+	var result systemIDForMACResult
+
+	if err := r.client.Get(ctx, fmt.Sprintf(systemIDForMACPath, mac), &result); err != nil {
+		return "", false, fmt.Errorf("resolving system id for %s: %w", mac, err)
+	}
+
+	return result.SystemID, result.IsBootInterface, nil
+}
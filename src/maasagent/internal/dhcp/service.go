@@ -17,19 +17,43 @@ package dhcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"time"
 
 	"go.temporal.io/sdk/activity"
+	tclient "go.temporal.io/sdk/client"
 	tworkflow "go.temporal.io/sdk/workflow"
 	"maas.io/core/src/maasagent/internal/apiclient"
 )
 
+// configAPIPath is the region controller endpoint DHCPService.update polls
+// for the rendered DHCP configuration (subnets, reservations, OMAPI key).
+const configAPIPath = "/api/2.0/dhcp/%s/config/"
+
 // DHCPService is a service that is responsible for setting up DHCP on MAAS Agent.
 type DHCPService struct {
 	fatal    chan error
 	client   *apiclient.APIClient
+	temporal tclient.Client
 	systemID string
-	running  bool
+	confDir  string
+
+	running bool
+
+	dhcp4 *daemonSupervisor
+	dhcp6 *daemonSupervisor
+	omapi *omapiClient
+	tail  *leaseTailer
+
+	// runCtx is the context supervise goroutines are launched under,
+	// cancelled by cancel on stop. update uses it to launch a
+	// supervise goroutine for a daemon (e.g. dhcp6) that's constructed
+	// after start has already run.
+	runCtx context.Context
+	cancel context.CancelFunc
 }
 
 type DHCPServiceOption func(*DHCPService)
@@ -37,6 +61,8 @@ type DHCPServiceOption func(*DHCPService)
 func NewDHCPService(systemID string, options ...DHCPServiceOption) *DHCPService {
 	s := &DHCPService{
 		systemID: systemID,
+		confDir:  "/var/lib/maas",
+		fatal:    make(chan error, 1),
 	}
 
 	for _, opt := range options {
@@ -54,6 +80,27 @@ func WithAPIClient(c *apiclient.APIClient) DHCPServiceOption {
 	}
 }
 
+// WithTemporalClient sets the Temporal client used to signal
+// "leases:<system_id>" workflows as new DHCP leases are observed.
+func WithTemporalClient(c tclient.Client) DHCPServiceOption {
+	return func(s *DHCPService) {
+		s.temporal = c
+	}
+}
+
+// WithConfDir overrides the directory DHCPService renders dhcpd.conf,
+// dhcpd6.conf and reads dhcpd.leases from (default: "/var/lib/maas").
+func WithConfDir(dir string) DHCPServiceOption {
+	return func(s *DHCPService) {
+		s.confDir = dir
+	}
+}
+
+// Name implements agent.Service.
+func (s *DHCPService) Name() string {
+	return "dhcp"
+}
+
 func (s *DHCPService) ConfigurationWorkflows() map[string]interface{} {
 	return map[string]interface{}{"configure-dhcp-service": s.configure}
 }
@@ -93,30 +140,262 @@ func (s *DHCPService) configure(ctx tworkflow.Context, config DHCPServiceConfigP
 	return run(ctx, s.update)
 }
 
+// start renders the initial configuration, validates it, and launches the
+// supervised dhcpd/dhcpd6 processes along with the lease tailer that feeds
+// the "leases:<system_id>" Temporal signal stream.
 func (s *DHCPService) start(ctx context.Context) error {
-	// TODO: start processing loop
+	if s.running {
+		return nil
+	}
+
+	if err := s.update(ctx); err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.runCtx = runCtx
+	s.cancel = cancel
+
+	s.tail = newLeaseTailer(s.leasesPath(), s.temporal, &apiClientResolver{client: s.client})
+	s.tail.onError = func(err error) { s.reportFatal(err) }
+
+	go s.supervise(runCtx, s.dhcp4)
+
+	if s.dhcp6 != nil {
+		go s.supervise(runCtx, s.dhcp6)
+	}
+
+	go func() {
+		if err := s.tail.run(runCtx); err != nil {
+			s.reportFatal(fmt.Errorf("lease tailer: %w", err))
+		}
+	}()
+
 	s.running = true
+
 	return nil
 }
 
+// supervise starts d and blocks waiting for it to exit, reporting an
+// unexpected exit on the fatal channel unless ctx was cancelled first
+// (i.e. a deliberate stop).
+func (s *DHCPService) supervise(ctx context.Context, d *daemonSupervisor) {
+	if err := d.start(ctx); err != nil {
+		s.reportFatal(fmt.Errorf("%s: %w", d.name, err))
+		return
+	}
+
+	err := d.wait()
+
+	select {
+	case <-ctx.Done():
+		// Stop was requested; exit is expected.
+		return
+	default:
+	}
+
+	if err != nil {
+		s.reportFatal(fmt.Errorf("%s exited: %w", d.name, err))
+	}
+}
+
+func (s *DHCPService) reportFatal(err error) {
+	select {
+	case s.fatal <- err:
+	default:
+	}
+}
+
+// stop tears down the supervised daemons and lease tailer started by start.
 func (s *DHCPService) stop(ctx context.Context) error {
-	// TODO: stop processing loop & clean up resources
+	if !s.running {
+		return nil
+	}
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	var firstErr error
+
+	for _, d := range []*daemonSupervisor{s.dhcp4, s.dhcp6} {
+		if d == nil {
+			continue
+		}
+
+		if err := d.stop(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
 	s.running = false
 
-	return nil
+	return firstErr
 }
 
+// update fetches the latest DHCP configuration from the region controller
+// and renders it. Before start has run, it stops there. Once running, it
+// starts dhcpd6 if IPv6 subnets have appeared since, then pushes the IPv4
+// host reservations over OMAPI when an OMAPI key is configured; without
+// one, or if OMAPI fails, it validates and reloads the daemons instead.
 func (s *DHCPService) update(ctx context.Context) error {
 	log := activity.GetLogger(ctx)
-	// TODO: API call to get config and template into dhcpd.conf
-	log.Debug("DHCPService update in progress..")
+
+	var cfg Config
+
+	if err := s.client.Get(ctx, fmt.Sprintf(configAPIPath, s.systemID), &cfg); err != nil {
+		return fmt.Errorf("fetching dhcp configuration: %w", err)
+	}
+
+	cfg4, cfg6 := splitConfigByFamily(cfg)
+
+	if s.dhcp4 == nil {
+		s.dhcp4 = newDaemonSupervisor("dhcpd", false, s.confPath("dhcpd.conf"),
+			s.confPath("dhcpd.leases"), s.confPath("dhcpd.pid"), cfg.Interfaces)
+	}
+
+	if err := renderConfig(s.confPath("dhcpd.conf"), cfg4); err != nil {
+		return err
+	}
+
+	dhcp6Created := false
+
+	if len(cfg6.Subnets) > 0 {
+		if s.dhcp6 == nil {
+			s.dhcp6 = newDaemonSupervisor("dhcpd6", true, s.confPath("dhcpd6.conf"),
+				s.confPath("dhcpd6.leases"), s.confPath("dhcpd6.pid"), cfg.Interfaces)
+			dhcp6Created = true
+		}
+
+		if err := renderConfig(s.confPath("dhcpd6.conf"), cfg6); err != nil {
+			return err
+		}
+	}
+
+	if cfg.OMAPIKey != "" {
+		s.omapi = newOMAPIClient("127.0.0.1", cfg.OMAPIPort, []byte(cfg.OMAPIKey))
+	}
+
+	if !s.running {
+		log.Debug("DHCPService not yet running, configuration will be picked up on start")
+		return nil
+	}
+
+	if dhcp6Created {
+		// v6 subnets appeared after start already launched its
+		// supervise goroutines, so dhcp6 was never started (and
+		// reloadDaemon below would hit "dhcpd6 is not running").
+		// Start it directly and give it its own supervisor.
+		if err := s.dhcp6.start(ctx); err != nil {
+			return fmt.Errorf("starting dhcpd6: %w", err)
+		}
+
+		go s.supervise(s.runCtx, s.dhcp6)
+	}
+
+	if s.omapi != nil {
+		err := s.updateViaOMAPI(ctx, cfg4)
+		if err == nil {
+			return nil
+		}
+
+		log.Warn("falling back to full dhcpd reload", "error", err)
+	}
+
+	if err := s.reloadDaemon(ctx, s.dhcp4); err != nil {
+		return err
+	}
+
+	if s.dhcp6 != nil && !dhcp6Created {
+		if err := s.reloadDaemon(ctx, s.dhcp6); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reloadDaemon validates d's currently rendered configuration and
+// restarts d against it (see daemonSupervisor.reload).
+func (s *DHCPService) reloadDaemon(ctx context.Context, d *daemonSupervisor) error {
+	if err := d.validate(ctx); err != nil {
+		return fmt.Errorf("validating %s: %w", d.name, err)
+	}
+
+	return d.reload(ctx)
+}
+
+// updateViaOMAPI pushes host reservations to the running daemon without a
+// restart. It does not attempt to reconcile deletions of reservations that
+// existed before the agent started; those still require a full reload.
+func (s *DHCPService) updateViaOMAPI(ctx context.Context, cfg Config) error {
+	for _, h := range cfg.Hosts {
+		if err := s.omapi.AddHost(ctx, h); err != nil {
+			return fmt.Errorf("adding host %s via omapi: %w", h.Hostname, err)
+		}
+	}
 
 	return nil
 }
 
+func (s *DHCPService) confPath(name string) string {
+	return filepath.Join(s.confDir, name)
+}
+
+func (s *DHCPService) leasesPath() string {
+	return s.confPath("dhcpd.leases")
+}
+
 func (s *DHCPService) Error() error {
 	err := <-s.fatal
 	s.running = false
 
 	return err
 }
+
+// Configure implements agent.Service, applying cfg immediately outside
+// of any Temporal workflow. ServiceManager.Configure calls it while this
+// service is enabled; the region controller's own Temporal-driven
+// configuration still goes through ConfigurationWorkflows.
+func (s *DHCPService) Configure(ctx context.Context, cfg json.RawMessage) error {
+	var param DHCPServiceConfigParam
+
+	if err := json.Unmarshal(cfg, &param); err != nil {
+		return fmt.Errorf("unmarshalling dhcp service config: %w", err)
+	}
+
+	if !param.Enabled {
+		return s.stop(ctx)
+	}
+
+	if s.running {
+		// Already running: start would be a no-op, so refresh the
+		// configuration on the running daemons directly.
+		return s.update(ctx)
+	}
+
+	// start already calls update itself, to render the initial
+	// configuration before launching the daemons and their supervise
+	// goroutines; calling update again immediately after would race
+	// those goroutines (e.g. reloadLocked seeing the daemon as not
+	// started yet).
+	return s.start(ctx)
+}
+
+// ErrNotRunning is returned by Health when the service has not been
+// started (or has been stopped/disabled).
+var ErrNotRunning = errors.New("dhcp: service is not running")
+
+// Health implements agent.Service.
+func (s *DHCPService) Health(ctx context.Context) error {
+	if !s.running {
+		return ErrNotRunning
+	}
+
+	return nil
+}
+
+// Stop implements agent.Service.
+func (s *DHCPService) Stop(ctx context.Context) error {
+	return s.stop(ctx)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package dhcp
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"text/template"
+)
+
+// Subnet describes a single DHCP-served subnet as returned by the region
+// controller. It carries just enough information to render the
+// corresponding dhcpd.conf subnet declaration.
+type Subnet struct {
+	CIDR       string   `json:"cidr"`
+	GatewayIP  string   `json:"gateway_ip"`
+	RangeLow   string   `json:"range_low"`
+	RangeHigh  string   `json:"range_high"`
+	DNSServers []string `json:"dns_servers"`
+	NTPServers []string `json:"ntp_servers"`
+	DomainName string   `json:"domain_name"`
+}
+
+// Host is a static reservation that should always be handed the same IP.
+type Host struct {
+	Hostname   string `json:"hostname"`
+	MACAddress string `json:"mac_address"`
+	IPAddress  string `json:"ip_address"`
+}
+
+// Config is the rendering input for the dhcpd.conf / dhcpd6.conf templates,
+// fetched from the region controller by (*DHCPService).update.
+type Config struct {
+	Interfaces []string `json:"interfaces"`
+	OMAPIKey   string   `json:"omapi_key"`
+	OMAPIPort  int      `json:"omapi_port"`
+	Subnets    []Subnet `json:"subnets"`
+	Hosts      []Host   `json:"hosts"`
+}
+
+const dhcpdConfTemplate = `# Generated by MAAS Agent. Do not edit.
+{{- if .OMAPIKey }}
+key maas-key {
+    algorithm hmac-md5;
+    secret "{{ .OMAPIKey }}";
+}
+omapi-port {{ .OMAPIPort }};
+omapi-key maas-key;
+{{- end }}
+{{- range .Subnets }}
+
+subnet {{ networkAddr .CIDR }} netmask {{ netmask .CIDR }} {
+    range {{ .RangeLow }} {{ .RangeHigh }};
+    option routers {{ .GatewayIP }};
+{{- if .DNSServers }}
+    option domain-name-servers {{ join .DNSServers }};
+{{- end }}
+{{- if .NTPServers }}
+    option ntp-servers {{ join .NTPServers }};
+{{- end }}
+{{- if .DomainName }}
+    option domain-name "{{ .DomainName }}";
+{{- end }}
+}
+{{- end }}
+{{- range .Hosts }}
+
+host {{ .Hostname }} {
+    hardware ethernet {{ .MACAddress }};
+    fixed-address {{ .IPAddress }};
+}
+{{- end }}
+`
+
+var dhcpdTemplate = template.Must(template.New("dhcpd.conf").Funcs(template.FuncMap{
+	"join": func(s []string) string {
+		var buf bytes.Buffer
+
+		for i, v := range s {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+
+			buf.WriteString(v)
+		}
+
+		return buf.String()
+	},
+	"networkAddr": subnetNetworkAddr,
+	"netmask":     subnetNetmask,
+}).Parse(dhcpdConfTemplate))
+
+// subnetNetworkAddr returns the bare network address of cidr (e.g.
+// "10.5.5.0" for "10.5.5.0/24"), as dhcpd.conf's "subnet" declaration
+// requires — it is a parse error on a real dhcpd to pass it a CIDR.
+func subnetNetworkAddr(cidr string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("parsing subnet CIDR %q: %w", cidr, err)
+	}
+
+	return ipNet.IP.String(), nil
+}
+
+// subnetNetmask returns the dotted-decimal netmask for cidr, derived
+// from its prefix length rather than assumed to be /24.
+func subnetNetmask(cidr string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("parsing subnet CIDR %q: %w", cidr, err)
+	}
+
+	return net.IP(ipNet.Mask).String(), nil
+}
+
+// splitConfigByFamily partitions cfg's subnets and hosts into an
+// IPv4-only Config (for dhcpd.conf) and an IPv6-only Config (for
+// dhcpd6.conf); both share cfg's interfaces and OMAPI settings. Entries
+// whose address fails to parse are dropped from both.
+func splitConfigByFamily(cfg Config) (v4, v6 Config) {
+	v4 = Config{Interfaces: cfg.Interfaces, OMAPIKey: cfg.OMAPIKey, OMAPIPort: cfg.OMAPIPort}
+	v6 = Config{Interfaces: cfg.Interfaces, OMAPIKey: cfg.OMAPIKey, OMAPIPort: cfg.OMAPIPort}
+
+	for _, s := range cfg.Subnets {
+		_, ipNet, err := net.ParseCIDR(s.CIDR)
+		if err != nil {
+			continue
+		}
+
+		if ipNet.IP.To4() != nil {
+			v4.Subnets = append(v4.Subnets, s)
+		} else {
+			v6.Subnets = append(v6.Subnets, s)
+		}
+	}
+
+	for _, h := range cfg.Hosts {
+		ip := net.ParseIP(h.IPAddress)
+		if ip == nil {
+			continue
+		}
+
+		if ip.To4() != nil {
+			v4.Hosts = append(v4.Hosts, h)
+		} else {
+			v6.Hosts = append(v6.Hosts, h)
+		}
+	}
+
+	return v4, v6
+}
+
+// renderConfig renders cfg into path using the dhcpd.conf template and
+// writes it atomically (render to a temp file, then rename) so a reload
+// never races a half-written config.
+func renderConfig(path string, cfg Config) error {
+	var buf bytes.Buffer
+
+	if err := dhcpdTemplate.Execute(&buf, cfg); err != nil {
+		return fmt.Errorf("rendering %s: %w", path, err)
+	}
+
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("installing %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package netmon
+
+import (
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LeaseKind distinguishes how a LeaseObservation was derived.
+type LeaseKind int
+
+const (
+	// LeaseKindDHCPv4 is a lease observed from a DHCPv4 ACK/offer.
+	LeaseKindDHCPv4 LeaseKind = iota
+	// LeaseKindDHCPv6 is a lease observed from a DHCPv6 reply's IA_NA/IA_TA.
+	LeaseKindDHCPv6
+)
+
+// LeaseObservation is a DHCP lease passively observed on the wire. Unlike
+// IPHwAddressPair (derived from any IP traffic), this is derived
+// specifically from DHCP/DHCPv6 protocol messages, so it additionally
+// carries the lease metadata DHCP exchanges: the client's requested
+// hostname, which server handed it out, and how long it's valid for.
+//
+// Observing these lets the agent detect new leases even when it isn't
+// the DHCP server itself, e.g. mixed environments with an upstream
+// router serving DHCP for a VLAN MAAS only monitors.
+type LeaseObservation struct {
+	IP        netip.Addr
+	HwAddress net.HardwareAddr
+	ClientID  string
+	Hostname  string
+	LeaseTime time.Duration
+	ServerID  netip.Addr
+	// ServerDUID is the DHCPv6 server's DUID (RFC 8415 section 11), an
+	// opaque identifier rather than an address, so it can't share
+	// ServerID above. Only set by getDHCPv6LeaseObservation.
+	ServerDUID string
+	Kind       LeaseKind
+}
+
+// getDHCPv4LeaseObservation decodes a BOOTP/DHCPv4 packet into a
+// LeaseObservation if it is an OFFER or ACK (the two message types that
+// actually hand out an address), returning false otherwise.
+func getDHCPv4LeaseObservation(packet gopacket.Packet) (LeaseObservation, bool) {
+	layer := packet.Layer(layers.LayerTypeDHCPv4)
+	if layer == nil {
+		return LeaseObservation{}, false
+	}
+
+	dhcp, ok := layer.(*layers.DHCPv4)
+	if !ok {
+		return LeaseObservation{}, false
+	}
+
+	obs := LeaseObservation{
+		HwAddress: dhcp.ClientHWAddr,
+		Kind:      LeaseKindDHCPv4,
+	}
+
+	var msgType layers.DHCPMsgType
+
+	for _, opt := range dhcp.Options {
+		switch opt.Type {
+		case layers.DHCPOptMessageType:
+			if len(opt.Data) == 1 {
+				msgType = layers.DHCPMsgType(opt.Data[0])
+			}
+		case layers.DHCPOptServerID:
+			if addr, ok := netip.AddrFromSlice(opt.Data); ok {
+				obs.ServerID = addr
+			}
+		case layers.DHCPOptHostname:
+			obs.Hostname = string(opt.Data)
+		case layers.DHCPOptClientID:
+			obs.ClientID = string(opt.Data)
+		case layers.DHCPOptLeaseTime:
+			if len(opt.Data) == 4 {
+				obs.LeaseTime = time.Duration(be32(opt.Data)) * time.Second
+			}
+		}
+	}
+
+	if msgType != layers.DHCPMsgTypeAck && msgType != layers.DHCPMsgTypeOffer {
+		return LeaseObservation{}, false
+	}
+
+	if !obs.IP.IsValid() {
+		if addr, ok := netip.AddrFromSlice(dhcp.YourClientIP); ok && !addr.IsUnspecified() {
+			obs.IP = addr
+		}
+	}
+
+	// An ACK answering a DHCPINFORM (rather than a DISCOVER/REQUEST)
+	// hands out no address of its own: yiaddr is zero and option 50 is
+	// absent, with the client's existing address only available in
+	// ciaddr (RFC 2131 section 4.4.1).
+	if !obs.IP.IsValid() {
+		if addr, ok := netip.AddrFromSlice(dhcp.ClientIP); ok {
+			obs.IP = addr
+		}
+	}
+
+	return obs, obs.IP.IsValid()
+}
+
+// getDHCPv6LeaseObservation decodes a DHCPv6 reply into a
+// LeaseObservation built from the first IA_NA/IA_TA address found.
+func getDHCPv6LeaseObservation(packet gopacket.Packet) (LeaseObservation, bool) {
+	layer := packet.Layer(layers.LayerTypeDHCPv6)
+	if layer == nil {
+		return LeaseObservation{}, false
+	}
+
+	dhcp, ok := layer.(*layers.DHCPv6)
+	if !ok {
+		return LeaseObservation{}, false
+	}
+
+	if dhcp.MsgType != layers.DHCPv6MsgTypeReply {
+		return LeaseObservation{}, false
+	}
+
+	obs := LeaseObservation{Kind: LeaseKindDHCPv6}
+
+	for _, opt := range dhcp.Options {
+		switch opt.Code {
+		case layers.DHCPv6OptIANA, layers.DHCPv6OptIATA:
+			if addr, leaseTime, ok := decodeIAAddress(opt.Code, opt.Data); ok {
+				obs.IP = addr
+				obs.LeaseTime = leaseTime
+			}
+		case layers.DHCPv6OptClientID:
+			obs.ClientID = string(opt.Data)
+		case layers.DHCPv6OptServerID:
+			obs.ServerDUID = string(opt.Data)
+		case layers.DHCPv6OptClientFQDN:
+			obs.Hostname = string(opt.Data)
+		}
+	}
+
+	return obs, obs.IP.IsValid()
+}
+
+// decodeIAAddress extracts the first IAADDR sub-option's address and
+// preferred lifetime out of an IA_NA/IA_TA option payload (RFC 8415
+// sections 21.4, 21.5, 21.6). The fixed header preceding the
+// sub-options differs by option: IA_NA carries 4 bytes IAID, 4 bytes
+// T1, 4 bytes T2 (12 bytes total), while IA_TA carries only the 4
+// byte IAID. One sub-option (code 5) is an IAADDR record: 16 bytes
+// address, 4 bytes preferred lifetime, 4 bytes valid lifetime.
+func decodeIAAddress(optCode layers.DHCPv6Opt, data []byte) (netip.Addr, time.Duration, bool) {
+	const iaAddrOptionCode = 5
+
+	headerLen := 12
+	if optCode == layers.DHCPv6OptIATA {
+		headerLen = 4
+	}
+
+	if len(data) < headerLen {
+		return netip.Addr{}, 0, false
+	}
+
+	rest := data[headerLen:]
+
+	for len(rest) >= 4 {
+		code := be16(rest[0:2])
+		length := int(be16(rest[2:4]))
+
+		if len(rest) < 4+length {
+			break
+		}
+
+		body := rest[4 : 4+length]
+
+		if code == iaAddrOptionCode && len(body) >= 24 {
+			addr, ok := netip.AddrFromSlice(body[0:16])
+			if ok {
+				return addr, time.Duration(be32(body[16:20])) * time.Second, true
+			}
+		}
+
+		rest = rest[4+length:]
+	}
+
+	return netip.Addr{}, 0, false
+}
+
+func be16(b []byte) uint16 {
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+func be32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package netmon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDHCPv4LeaseObservation(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x16, 0x3e, 0xbc, 0x34, 0x46}
+
+	dhcp := &layers.DHCPv4{
+		Operation:    layers.DHCPOpReply,
+		HardwareType: layers.LinkTypeEthernet,
+		HardwareLen:  uint8(len(mac)),
+		ClientHWAddr: mac,
+		YourClientIP: net.ParseIP("172.16.1.20").To4(),
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeAck)}),
+			layers.NewDHCPOption(layers.DHCPOptHostname, []byte("test-node")),
+		},
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, dhcp)
+	assert.NoError(t, err)
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeDHCPv4, gopacket.Default)
+
+	obs, ok := getDHCPv4LeaseObservation(packet)
+	assert.True(t, ok)
+	assert.Equal(t, "172.16.1.20", obs.IP.String())
+	assert.Equal(t, mac, obs.HwAddress)
+	assert.Equal(t, "test-node", obs.Hostname)
+	assert.Equal(t, LeaseKindDHCPv4, obs.Kind)
+}
+
+func TestGetDHCPv4LeaseObservationIgnoresDiscover(t *testing.T) {
+	dhcp := &layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		HardwareType: layers.LinkTypeEthernet,
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeDiscover)}),
+		},
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, dhcp)
+	assert.NoError(t, err)
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeDHCPv4, gopacket.Default)
+
+	_, ok := getDHCPv4LeaseObservation(packet)
+	assert.False(t, ok)
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package netmon
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// layerTypeDHCPv4Raw decodes a bare DHCPv4 payload (no Ethernet/IP/UDP
+// headers), which is all ScanDHCPInform has to work with since it reads
+// from a UDP socket rather than a raw capture handle.
+var layerTypeDHCPv4Raw = layers.LayerTypeDHCPv4
+
+const dhcpServerPort = 67
+
+// sendDHCPInform broadcasts a DHCPINFORM for ip: a client that already
+// has an address asking "what would my configuration be", which dhcpd
+// answers without allocating or renewing a lease.
+func sendDHCPInform(conn net.PacketConn, ip netip.Addr, hwAddr net.HardwareAddr) error {
+	dhcp := layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		HardwareType: layers.LinkTypeEthernet,
+		HardwareLen:  uint8(len(hwAddr)),
+		Xid:          uint32(time.Now().UnixNano()), //nolint:gosec // correlation only
+		ClientIP:     net.IP(ip.AsSlice()),
+		ClientHWAddr: hwAddr,
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeInform)}),
+		},
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, &dhcp)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.WriteTo(buf.Bytes(), &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpServerPort})
+
+	return err
+}
+
+// Observation is either an IPHwAddressPair derived from any sniffed IP
+// traffic, or a LeaseObservation derived specifically from a DHCP/DHCPv6
+// exchange. Exactly one of the two fields is non-zero.
+type Observation struct {
+	Pair  *IPHwAddressPair
+	Lease *LeaseObservation
+}
+
+// Observe reads packets from src until it's exhausted or ctx is
+// cancelled, emitting Observations on the returned channel:
+// an ARP/NDP-style IPHwAddressPair for every IP packet, plus a
+// LeaseObservation whenever the packet turns out to also be a DHCP or
+// DHCPv6 lease offer/ack/reply. This is what lets the agent see new
+// leases on a network even when it isn't the DHCP server for it, e.g. a
+// VLAN with an upstream router handing out addresses.
+func Observe(ctx context.Context, src *gopacket.PacketSource) <-chan Observation {
+	out := make(chan Observation)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case packet, ok := <-src.Packets():
+				if !ok {
+					return
+				}
+
+				if pair := getIPHwAddressPair(packet); pair.IP.IsValid() {
+					out <- Observation{Pair: &pair}
+				}
+
+				if lease, ok := getDHCPv4LeaseObservation(packet); ok {
+					out <- Observation{Lease: &lease}
+				}
+
+				if lease, ok := getDHCPv6LeaseObservation(packet); ok {
+					out <- Observation{Lease: &lease}
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// dhcpInformTimeout bounds how long ScanDHCPInform keeps reading
+// responses: it stops once this long passes without any packet arriving
+// on its socket.
+const dhcpInformTimeout = 2 * time.Second
+
+// ScanDHCPInform solicits a DHCPACK for each address in ips by sending a
+// DHCPINFORM (RFC 2131 section 4.4.1), without requesting or disturbing
+// any lease the address may already have, unlike a DHCPDISCOVER/REQUEST
+// cycle. This lets a Scan confirm liveness/configuration on addresses
+// MAAS suspects are in use without ever claiming them.
+func ScanDHCPInform(ctx context.Context, ips []netip.Addr, localHwAddr net.HardwareAddr) ([]LeaseObservation, error) {
+	conn, err := net.ListenPacket("udp4", ":68")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var observations []LeaseObservation
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		buf := make([]byte, 1500)
+
+		for {
+			if err := conn.SetReadDeadline(time.Now().Add(dhcpInformTimeout)); err != nil {
+				return
+			}
+
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			packet := gopacket.NewPacket(buf[:n], layerTypeDHCPv4Raw, gopacket.Default)
+			if lease, ok := getDHCPv4LeaseObservation(packet); ok {
+				observations = append(observations, lease)
+			}
+		}
+	}()
+
+	for _, ip := range ips {
+		if !ip.Is4() {
+			continue
+		}
+
+		if err := sendDHCPInform(conn, ip, localHwAddr); err != nil {
+			return nil, err
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-done:
+	}
+
+	return observations, nil
+}
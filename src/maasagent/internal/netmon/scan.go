@@ -0,0 +1,189 @@
+// Copyright (c) 2023-2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package netmon implements passive and active discovery of hosts on
+// networks MAAS Agent monitors: recording which hardware address is
+// using which IP, independent of whether MAAS itself is serving DHCP on
+// that network.
+package netmon
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// scanTimeout bounds how long Scan keeps reading ICMP echo replies: it
+// stops once this long passes without any packet arriving on its socket.
+const scanTimeout = 2 * time.Second
+
+// IPHwAddressPair associates an observed IP address with the hardware
+// address that was seen using it, regardless of how it was observed
+// (ARP, NDP, or simply as the source of any sniffed IP packet).
+type IPHwAddressPair struct {
+	IP        netip.Addr
+	HwAddress net.HardwareAddr
+}
+
+// getIPHwAddressPair extracts the source IP and source hardware address
+// from an Ethernet+IP packet. This is the cheapest possible form of
+// passive discovery: any host that sends IP traffic reveals which MAC is
+// using which IP, whether or not MAAS is the DHCP server for that
+// network.
+func getIPHwAddressPair(packet gopacket.Packet) IPHwAddressPair {
+	var pair IPHwAddressPair
+
+	ethLayer := packet.Layer(layers.LayerTypeEthernet)
+	if ethLayer == nil {
+		return pair
+	}
+
+	eth, ok := ethLayer.(*layers.Ethernet)
+	if !ok {
+		return pair
+	}
+
+	pair.HwAddress = eth.SrcMAC
+
+	if ipLayer := packet.Layer(layers.LayerTypeIPv4); ipLayer != nil {
+		if ip, ok := ipLayer.(*layers.IPv4); ok {
+			if addr, ok := netip.AddrFromSlice(ip.SrcIP); ok {
+				pair.IP = addr
+			}
+		}
+	} else if ipLayer := packet.Layer(layers.LayerTypeIPv6); ipLayer != nil {
+		if ip, ok := ipLayer.(*layers.IPv6); ok {
+			if addr, ok := netip.AddrFromSlice(ip.SrcIP); ok {
+				pair.IP = addr
+			}
+		}
+	}
+
+	return pair
+}
+
+// Scan pings every address in ips using a privileged (raw socket) ICMP
+// echo, and returns the subset that responded before reading stopped
+// (see scanTimeout).
+//
+// sudo is required because Scan uses a raw ICMP socket:
+//
+//	sudo TEST_NETMON_SCAN=172.16.1.1,172.16.2.1 \
+//	  go test maas.io/core/src/maasagent/internal/netmon -run TestScan -count 1 -v
+func Scan(ctx context.Context, ips []netip.Addr) ([]netip.Addr, error) {
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var (
+		mu    sync.Mutex
+		alive []netip.Addr
+	)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		buf := make([]byte, 1500)
+
+		for {
+			if err := conn.SetReadDeadline(time.Now().Add(scanTimeout)); err != nil {
+				return
+			}
+
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			msg, err := icmp.ParseMessage(1, buf[:n])
+			if err != nil || msg.Type != ipv4.ICMPTypeEchoReply {
+				continue
+			}
+
+			udpAddr, ok := peer.(*net.IPAddr)
+			if !ok {
+				continue
+			}
+
+			addr, ok := netip.AddrFromSlice(udpAddr.IP.To4())
+			if !ok {
+				continue
+			}
+
+			mu.Lock()
+			alive = append(alive, addr)
+			mu.Unlock()
+		}
+	}()
+
+	if err := sendEchoRequests(conn, ips); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-done:
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return alive, nil
+}
+
+func sendEchoRequests(conn *icmp.PacketConn, ips []netip.Addr) error {
+	for i, addr := range ips {
+		if !addr.Is4() {
+			continue
+		}
+
+		msg := icmp.Message{
+			Type: ipv4.ICMPTypeEcho,
+			Code: 0,
+			Body: &icmp.Echo{
+				ID:   scanICMPID(),
+				Seq:  i,
+				Data: []byte("maas-netmon"),
+			},
+		}
+
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err := conn.WriteTo(b, &net.IPAddr{IP: net.IP(addr.AsSlice())}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanICMPID() int {
+	return int(uint16(time.Now().UnixNano())) //nolint:gosec // used only to correlate our own probes
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package workflow
+
+import (
+	"go.temporal.io/sdk/workflow"
+)
+
+// SagaStep is a single unit of work registered with a Saga: Forward
+// performs the step, and Compensate (optional, nil to skip) undoes it.
+type SagaStep struct {
+	Forward    func(ctx workflow.Context) error
+	Compensate func(ctx workflow.Context) error
+}
+
+// Saga runs a sequence of steps, and if any step fails, rolls back every
+// previously successful step's Compensate in reverse order. This is for
+// workflows like Deploy that touch several pieces of external state
+// (allocated IPs, boot order, node status) where a mid-sequence failure
+// must not leave that state half-applied.
+type Saga struct {
+	steps []SagaStep
+}
+
+// NewSaga returns an empty Saga ready to have steps added via AddStep.
+func NewSaga() *Saga {
+	return &Saga{}
+}
+
+// AddStep appends a step to the saga. compensate may be nil if the step
+// has nothing to undo (e.g. a read-only activity).
+func (s *Saga) AddStep(forward, compensate func(ctx workflow.Context) error) {
+	s.steps = append(s.steps, SagaStep{Forward: forward, Compensate: compensate})
+}
+
+// Execute runs each step's Forward in order. If one fails, every
+// previously completed step is compensated in reverse order using a
+// disconnected context, so compensation still runs even if ctx was
+// cancelled, then the original forward error is returned.
+func (s *Saga) Execute(ctx workflow.Context) error {
+	for i, step := range s.steps {
+		if err := step.Forward(ctx); err != nil {
+			s.compensate(ctx, s.steps[:i])
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Saga) compensate(ctx workflow.Context, completed []SagaStep) {
+	disconnectedCtx, cancel := workflow.NewDisconnectedContext(ctx)
+	defer cancel()
+
+	logger := workflow.GetLogger(ctx)
+
+	for i := len(completed) - 1; i >= 0; i-- {
+		compensate := completed[i].Compensate
+		if compensate == nil {
+			continue
+		}
+
+		if err := compensate(disconnectedCtx); err != nil {
+			logger.Error("saga compensation step failed", "error", err)
+		}
+	}
+}
@@ -16,12 +16,16 @@ package workflow
 
 import (
 	"errors"
+	"time"
 
 	"go.temporal.io/sdk/workflow"
 )
 
 var (
 	ErrSignalClosed = errors.New("requested signal closed")
+	// ErrSignalTimeout is returned by HandleSignalWithTimeout when no
+	// matching signal arrives before the given timeout elapses.
+	ErrSignalTimeout = errors.New("timed out waiting for signal")
 )
 
 type LeaseSignal struct {
@@ -52,6 +56,14 @@ type CloudInitFinished struct {
 	SystemID string `json:"system_id"`
 }
 
+// DeployCancelledSignal is sent to interrupt a running deploy, e.g. when a
+// user cancels a deployment from the region controller UI while the
+// workflow is waiting on curtin or cloud-init.
+type DeployCancelledSignal struct {
+	SystemID string `json:"system_id"`
+	Reason   string `json:"reason"`
+}
+
 func HandleSignal[T any](ctx workflow.Context, channel string) (*T, error) {
 	var signal T
 
@@ -62,3 +74,108 @@ func HandleSignal[T any](ctx workflow.Context, channel string) (*T, error) {
 
 	return &signal, nil
 }
+
+// HandleSignalWithTimeout waits for a single signal on channel, returning
+// ErrSignalTimeout if none arrives within timeout. Use this in place of
+// HandleSignal when the workflow must not block forever on a signal that
+// may never come (e.g. a deploy that should fail rather than hang if a
+// lease never shows up).
+func HandleSignalWithTimeout[T any](ctx workflow.Context, channel string, timeout time.Duration) (*T, error) {
+	var (
+		signal   T
+		received bool
+		closed   bool
+		timedOut bool
+	)
+
+	sigChan := workflow.GetSignalChannel(ctx, channel)
+	timer := workflow.NewTimer(ctx, timeout)
+
+	selector := workflow.NewSelector(ctx)
+	selector.AddReceive(sigChan, func(c workflow.ReceiveChannel, more bool) {
+		if !c.Receive(ctx, &signal) {
+			closed = true
+			return
+		}
+
+		received = true
+	})
+	selector.AddFuture(timer, func(workflow.Future) {
+		timedOut = true
+	})
+
+	selector.Select(ctx)
+
+	switch {
+	case received:
+		return &signal, nil
+	case closed:
+		return nil, ErrSignalClosed
+	case timedOut:
+		return nil, ErrSignalTimeout
+	default:
+		return nil, ErrSignalTimeout
+	}
+}
+
+// HandleSignalPredicate drains channel, discarding signals that don't
+// satisfy match, until one does. This lets callers like
+// checkForBootInterfaceLease filter a shared signal stream (e.g. leases
+// for interfaces other than the boot interface) in a single call instead
+// of hand-rolling the receive loop themselves.
+func HandleSignalPredicate[T any](ctx workflow.Context, channel string, match func(T) bool) (*T, error) {
+	sigChan := workflow.GetSignalChannel(ctx, channel)
+
+	for {
+		var signal T
+		if !sigChan.Receive(ctx, &signal) {
+			return nil, ErrSignalClosed
+		}
+
+		if match(signal) {
+			return &signal, nil
+		}
+	}
+}
+
+// SignalCase pairs a named signal channel with a typed callback, for use
+// with SelectSignals to race heterogeneous signals against each other
+// (e.g. "curtin-finished" vs. a "deploy-cancelled" signal that should
+// interrupt a long-running deploy workflow instead of it hanging on a
+// single Receive).
+type SignalCase[T any] struct {
+	Channel string
+	Handler func(T)
+}
+
+// signalCase is the type-erased form of SignalCase[T] used internally so
+// SelectSignals can accept cases of differing T in the same call.
+type signalCase interface {
+	register(ctx workflow.Context, selector workflow.Selector)
+}
+
+func (c SignalCase[T]) register(ctx workflow.Context, selector workflow.Selector) {
+	sigChan := workflow.GetSignalChannel(ctx, c.Channel)
+	selector.AddReceive(sigChan, func(ch workflow.ReceiveChannel, more bool) {
+		var signal T
+		if ch.Receive(ctx, &signal) {
+			c.Handler(signal)
+		}
+	})
+}
+
+// SelectSignals blocks until exactly one of cases fires, invoking its
+// Handler and returning. Cases are built with SignalCase[T]{Channel, Handler},
+// so calls with different signal types can be raced together, e.g. a
+// "curtin-finished" signal against a "deploy-cancelled" signal that should
+// interrupt a long-running deploy workflow instead of it hanging on a
+// single Receive.
+func SelectSignals(ctx workflow.Context, cases ...signalCase) {
+	selector := workflow.NewSelector(ctx)
+
+	for _, c := range cases {
+		c.register(ctx, selector)
+	}
+
+	selector.Select(ctx)
+}
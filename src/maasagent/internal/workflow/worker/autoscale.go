@@ -0,0 +1,166 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package worker
+
+import (
+	"context"
+	"time"
+
+	enums "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/worker"
+)
+
+// AutoscaleOptions configures a group's automatic worker count, keeping
+// the number of worker.Worker instances polling taskQueue close to
+// actual load instead of a fixed count set by hand.
+type AutoscaleOptions struct {
+	// Min and Max bound the number of workers the reconciler will run.
+	Min, Max int
+	// TargetBacklog is the backlog count the reconciler scales towards:
+	// above it, a worker is added; at or below half of it, a worker is
+	// removed (subject to Min/Max and Cooldown).
+	TargetBacklog int
+	// ScaleInterval is how often the reconciler polls DescribeTaskQueue
+	// for the group's backlog.
+	ScaleInterval time.Duration
+	// CooldownSec is the minimum number of seconds between two scaling
+	// decisions for the group, to avoid flapping.
+	CooldownSec int
+}
+
+func (o AutoscaleOptions) normalized() AutoscaleOptions {
+	if o.Min < 1 {
+		o.Min = 1
+	}
+
+	if o.Max < o.Min {
+		o.Max = o.Min
+	}
+
+	if o.ScaleInterval <= 0 {
+		o.ScaleInterval = 30 * time.Second
+	}
+
+	return o
+}
+
+// AddAutoscaledWorkers starts a group with opts.Min workers and launches
+// a background reconciler that polls the task queue's backlog every
+// opts.ScaleInterval, adding workers (up to opts.Max) when backlog
+// exceeds opts.TargetBacklog and removing workers (down to opts.Min)
+// when it falls well below it. Removed workers are stopped via the same
+// worker.Stop used by RemoveWorkers, which blocks for in-flight
+// workflow/activity tasks to drain. The reconciler is stopped when the
+// group is removed via RemoveWorkers.
+func (p *WorkerPool) AddAutoscaledWorkers(group, taskQueue string,
+	workflows, activities map[string]interface{}, opts AutoscaleOptions) error {
+	opts = opts.normalized()
+
+	for i := 0; i < opts.Min; i++ {
+		if err := p.AddWorker(group, taskQueue, workflows, activities, worker.Options{}); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p.mutex.Lock()
+	p.autoscalers[group] = cancel
+	p.mutex.Unlock()
+
+	go p.runAutoscaler(ctx, group, taskQueue, workflows, activities, opts)
+
+	return nil
+}
+
+func (p *WorkerPool) runAutoscaler(ctx context.Context, group, taskQueue string,
+	workflows, activities map[string]interface{}, opts AutoscaleOptions) {
+	ticker := time.NewTicker(opts.ScaleInterval)
+	defer ticker.Stop()
+
+	cooldown := time.Duration(opts.CooldownSec) * time.Second
+
+	var lastScale time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if time.Since(lastScale) < cooldown {
+			continue
+		}
+
+		backlog, err := p.taskQueueBacklog(ctx, taskQueue)
+		if err != nil {
+			continue
+		}
+
+		p.mutex.Lock()
+		current := len(p.workers[group])
+		p.mutex.Unlock()
+
+		switch {
+		case backlog > int64(opts.TargetBacklog) && current < opts.Max:
+			if err := p.AddWorker(group, taskQueue, workflows, activities, worker.Options{}); err == nil {
+				lastScale = time.Now()
+			}
+		case backlog <= int64(opts.TargetBacklog)/2 && current > opts.Min:
+			p.removeNewestWorker(group)
+			lastScale = time.Now()
+		}
+	}
+}
+
+// taskQueueBacklog reports the backlog count hint for taskQueue's
+// workflow task queue.
+func (p *WorkerPool) taskQueueBacklog(ctx context.Context, taskQueue string) (int64, error) {
+	resp, err := p.client.DescribeTaskQueue(ctx, taskQueue, enums.TASK_QUEUE_TYPE_WORKFLOW)
+	if err != nil {
+		return 0, err
+	}
+
+	status := resp.GetTaskQueueStatus()
+	if status == nil {
+		return 0, nil
+	}
+
+	return status.GetBacklogCountHint(), nil
+}
+
+// removeNewestWorker stops and drops the most recently added worker in
+// group, leaving the rest of the group running.
+func (p *WorkerPool) removeNewestWorker(group string) {
+	p.mutex.Lock()
+	workers := p.workers[group]
+
+	if len(workers) == 0 {
+		p.mutex.Unlock()
+		return
+	}
+
+	w := workers[len(workers)-1]
+	remaining := workers[:len(workers)-1]
+	p.workers[group] = remaining
+	p.mutex.Unlock()
+
+	w.stopTracked()
+
+	workerStopsTotal.WithLabelValues(p.systemID, group).Inc()
+	workersGauge.WithLabelValues(p.systemID, group).Set(float64(len(remaining)))
+}
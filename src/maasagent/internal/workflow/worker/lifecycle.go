@@ -0,0 +1,259 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/worker"
+)
+
+// WorkerState is the current lifecycle state of a single worker within
+// a group, as reported by WorkersInGroup.
+type WorkerState int
+
+const (
+	WorkerStateRunning WorkerState = iota
+	WorkerStateDraining
+	WorkerStateStopped
+)
+
+func (s WorkerState) String() string {
+	switch s {
+	case WorkerStateDraining:
+		return "draining"
+	case WorkerStateStopped:
+		return "stopped"
+	default:
+		return "running"
+	}
+}
+
+// WorkerInfo describes a single worker within a group, as returned by
+// WorkersInGroup.
+type WorkerInfo struct {
+	TaskQueue string
+	StartedAt time.Time
+	State     WorkerState
+}
+
+// trackedWorker wraps a worker.Worker with the bookkeeping WorkerPool
+// needs for introspection (WorkersInGroup) and graceful drain
+// (DrainWorkers), neither of which worker.Worker itself exposes.
+type trackedWorker struct {
+	worker.Worker
+
+	taskQueue string
+	startedAt time.Time
+
+	mu    sync.Mutex
+	state WorkerState
+}
+
+func newTrackedWorker(w worker.Worker, taskQueue string) *trackedWorker {
+	return &trackedWorker{
+		Worker:    w,
+		taskQueue: taskQueue,
+		startedAt: time.Now(),
+		state:     WorkerStateRunning,
+	}
+}
+
+func (tw *trackedWorker) info() WorkerInfo {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	return WorkerInfo{
+		TaskQueue: tw.taskQueue,
+		StartedAt: tw.startedAt,
+		State:     tw.state,
+	}
+}
+
+// stopTracked stops the underlying worker, updating state around the
+// call so concurrent WorkersInGroup callers see it as draining.
+func (tw *trackedWorker) stopTracked() {
+	tw.mu.Lock()
+	tw.state = WorkerStateDraining
+	tw.mu.Unlock()
+
+	tw.Worker.Stop()
+
+	tw.mu.Lock()
+	tw.state = WorkerStateStopped
+	tw.mu.Unlock()
+}
+
+// groupLifecycle holds the PreStop/PostStop hooks registered for a
+// group via AddWorker's WorkerLifecycleOptions.
+type groupLifecycle struct {
+	preStop  func()
+	postStop func()
+}
+
+// WorkerLifecycleOption customizes drain behaviour for the group a
+// worker is added to via AddWorker/AddAutoscaledWorkers.
+type WorkerLifecycleOption func(*groupLifecycle)
+
+// WithPreStop registers a hook run once, before a group's workers are
+// stopped, whenever that group is drained via DrainWorkers or Shutdown.
+func WithPreStop(fn func()) WorkerLifecycleOption {
+	return func(l *groupLifecycle) {
+		l.preStop = fn
+	}
+}
+
+// WithPostStop registers a hook run once, after a group's workers have
+// stopped (or draining them timed out), whenever that group is drained
+// via DrainWorkers or Shutdown.
+func WithPostStop(fn func()) WorkerLifecycleOption {
+	return func(l *groupLifecycle) {
+		l.postStop = fn
+	}
+}
+
+// runHook invokes fn, if set, recovering any panic so a misbehaving
+// PreStop/PostStop hook can never prevent the drain it's attached to
+// from completing its own teardown.
+func runHook(fn func()) {
+	if fn == nil {
+		return
+	}
+
+	defer func() { recover() }() //nolint:errcheck
+
+	fn()
+}
+
+// DrainWorkers stops every worker in group the same way RemoveWorkers
+// does, but bounds the wait on ctx and fires any PreStop/PostStop hooks
+// registered for the group around the drain. Bookkeeping for the group
+// (workers, autoscaler, schedules, hooks) is torn down regardless of
+// whether the drain completed in time or a hook panicked.
+func (p *WorkerPool) DrainWorkers(ctx context.Context, group string) error {
+	p.mutex.Lock()
+	workers := append([]*trackedWorker(nil), p.workers[group]...)
+	hooks := p.hooks[group]
+	p.mutex.Unlock()
+
+	runHook(hooks.preStop)
+
+	var drainErr error
+
+	if len(workers) > 0 {
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			for _, tw := range workers {
+				tw.stopTracked()
+				workerStopsTotal.WithLabelValues(p.systemID, group).Inc()
+			}
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			drainErr = fmt.Errorf("draining group %q: in-flight work exceeded deadline: %w", group, ctx.Err())
+		}
+	}
+
+	runHook(hooks.postStop)
+
+	p.mutex.Lock()
+	p.teardownGroupLocked(group)
+	p.mutex.Unlock()
+
+	return drainErr
+}
+
+// Shutdown drains every group in reverse-registration order, then stops
+// the main control-plane worker. Each group's drain is bounded by ctx;
+// the first error encountered is returned only after every group has
+// been given a chance to drain.
+func (p *WorkerPool) Shutdown(ctx context.Context) error {
+	p.mutex.Lock()
+	groups := append([]string(nil), p.groupOrder...)
+	p.mutex.Unlock()
+
+	var firstErr error
+
+	for i := len(groups) - 1; i >= 0; i-- {
+		if err := p.DrainWorkers(ctx, groups[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	p.main.Stop()
+
+	p.mutex.Lock()
+	p.started = false
+	p.mutex.Unlock()
+
+	return firstErr
+}
+
+// WorkersInGroup reports the task queue, start time, and current state
+// of every worker registered under group.
+func (p *WorkerPool) WorkersInGroup(group string) []WorkerInfo {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	workers := p.workers[group]
+	infos := make([]WorkerInfo, len(workers))
+
+	for i, tw := range workers {
+		infos[i] = tw.info()
+	}
+
+	return infos
+}
+
+// teardownGroupLocked drops every piece of bookkeeping the pool keeps
+// for group once its workers have been stopped or drained. Callers must
+// hold p.mutex.
+func (p *WorkerPool) teardownGroupLocked(group string) {
+	if _, ok := p.workers[group]; ok {
+		delete(p.workers, group)
+		workersGauge.WithLabelValues(p.systemID, group).Set(0)
+	}
+
+	if cancel, ok := p.autoscalers[group]; ok {
+		cancel()
+		delete(p.autoscalers, group)
+	}
+
+	for name := range p.schedules[group] {
+		// Best-effort: the schedule may already be gone, or Temporal may
+		// be briefly unreachable. Nothing else references it once the
+		// group itself is gone, so there is nothing useful to retry.
+		p.scheduler.delete(context.Background(), group, name)
+	}
+
+	delete(p.schedules, group)
+	delete(p.hooks, group)
+
+	for i, g := range p.groupOrder {
+		if g == group {
+			p.groupOrder = append(p.groupOrder[:i], p.groupOrder[i+1:]...)
+			break
+		}
+	}
+}
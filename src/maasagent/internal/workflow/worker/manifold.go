@@ -0,0 +1,409 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// manifoldOutputPollInterval is how often supervise polls a live
+// Runner's Output for changes, so dependents pick up a new value
+// without waiting for the Runner supplying it to exit.
+const manifoldOutputPollInterval = 5 * time.Second
+
+// Runner is the running instance of a Manifold, as returned by its
+// Start function. The engine supervises it: waiting for it to exit,
+// restarting it (and anything depending on it) when it exits with an
+// error, and polling Output to detect changes that should trigger a
+// restart of dependents even while it keeps running.
+type Runner interface {
+	// Wait blocks until the runner exits, returning the error (if any)
+	// it exited with. A nil error is treated as a deliberate, graceful
+	// exit (e.g. a one-shot task manifold that runs to completion): it
+	// does not surface on the pool's fatal channel, and the manifold
+	// itself is not restarted, but its dependents still are, since
+	// Stop below was not necessarily called and its final Output may
+	// differ from what they last saw.
+	Wait() error
+	// Stop requests the runner shut down; a subsequent Wait should then
+	// return.
+	Stop()
+	// Output is the value downstream manifolds receive as their input
+	// under this manifold's name. While the runner is live, the engine
+	// polls Output every manifoldOutputPollInterval and restarts
+	// dependents when it differs from the value last seen, compared
+	// with == (mirroring Juju's dependency engine); Runners wanting change
+	// detection on non-comparable values should wrap them behind a
+	// stable, comparable handle.
+	Output() interface{}
+}
+
+// Manifold declares one node in WorkerPool's dependency graph: the
+// names of the other manifolds it depends on, and a Start function
+// producing the Runner the engine supervises as that node. Modeled on
+// Juju's dependency.Manifold, this replaces the flat WithConfigurator
+// registration (which forces every workflow/activity onto main with no
+// ordering or restart semantics) with a composable model for
+// subsystems that legitimately depend on each other (DHCP, DNS, image
+// sync, power, ...).
+type Manifold struct {
+	// Inputs names the manifolds this one depends on. The engine starts
+	// them first and passes their latest Output in the inputs map
+	// Start receives.
+	Inputs []string
+	// Start is called with the current Output of every named Input,
+	// keyed by manifold name, to produce this manifold's Runner. It is
+	// called again, with refreshed inputs, whenever this manifold or
+	// any of its inputs needs to (re)start.
+	Start func(ctx context.Context, inputs map[string]interface{}) (Runner, error)
+}
+
+type manifoldState struct {
+	runner Runner
+	output interface{}
+	cancel context.CancelFunc
+}
+
+// manifoldEngine computes a topological start order over registered
+// Manifolds, starts and supervises each one's Runner, and restarts
+// everything depending on a manifold (transitively) whenever its Runner
+// exits or its Output changes; the manifold itself is restarted only
+// when its Runner exits with an error.
+type manifoldEngine struct {
+	pool *WorkerPool
+
+	mu        sync.Mutex
+	manifolds map[string]Manifold
+	states    map[string]*manifoldState
+
+	// reconcileMu serializes every reconciliation (the initial start
+	// and every subsequent restart): it's held for the full
+	// topo-order-then-start sequence, so two manifolds exiting or
+	// changing Output around the same time are reconciled one at a
+	// time instead of computing overlapping affected sets and
+	// double-starting or orphaning a manifold.
+	reconcileMu sync.Mutex
+}
+
+func newManifoldEngine(pool *WorkerPool) *manifoldEngine {
+	return &manifoldEngine{
+		pool:      pool,
+		manifolds: make(map[string]Manifold),
+		states:    make(map[string]*manifoldState),
+	}
+}
+
+// add registers m under name, to be started the next time the engine
+// starts or reconciles.
+func (e *manifoldEngine) add(name string, m Manifold) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.manifolds[name] = m
+}
+
+// start computes a topological order over every registered manifold and
+// starts each one in turn.
+func (e *manifoldEngine) start(ctx context.Context) error {
+	e.reconcileMu.Lock()
+	defer e.reconcileMu.Unlock()
+
+	e.mu.Lock()
+	order, err := e.topoOrderLocked()
+	e.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		if err := e.startManifold(ctx, name); err != nil {
+			return fmt.Errorf("starting manifold %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// topoOrderLocked returns every registered manifold name in dependency
+// order (inputs before dependents). Callers must hold e.mu.
+func (e *manifoldEngine) topoOrderLocked() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(e.manifolds))
+	order := make([]string, 0, len(e.manifolds))
+
+	var visit func(name string) error
+
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("manifold dependency cycle detected at %q", name)
+		}
+
+		state[name] = visiting
+
+		m, ok := e.manifolds[name]
+		if !ok {
+			return fmt.Errorf("manifold %q depends on unregistered manifold", name)
+		}
+
+		for _, in := range m.Inputs {
+			if err := visit(in); err != nil {
+				return err
+			}
+		}
+
+		state[name] = visited
+		order = append(order, name)
+
+		return nil
+	}
+
+	for name := range e.manifolds {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// startManifold (re)starts the manifold registered under name, passing
+// it the latest Output of each of its inputs, and launches a goroutine
+// supervising the resulting Runner.
+func (e *manifoldEngine) startManifold(ctx context.Context, name string) error {
+	e.mu.Lock()
+	m, ok := e.manifolds[name]
+
+	if !ok {
+		e.mu.Unlock()
+		return fmt.Errorf("unknown manifold %q", name)
+	}
+
+	inputs := make(map[string]interface{}, len(m.Inputs))
+
+	for _, in := range m.Inputs {
+		if st, ok := e.states[in]; ok {
+			inputs[in] = st.output
+		}
+	}
+	e.mu.Unlock()
+
+	runner, err := m.Start(ctx, inputs)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+
+	e.mu.Lock()
+	e.states[name] = &manifoldState{
+		runner: runner,
+		output: runner.Output(),
+		cancel: cancel,
+	}
+	e.mu.Unlock()
+
+	go e.supervise(runCtx, name)
+
+	return nil
+}
+
+// supervise watches name's Runner until its supervising context is
+// cancelled: waiting for it to exit, and meanwhile polling its Output
+// for changes. An exit with a non-nil error, or an Output change,
+// restarts every manifold depending on name (transitively), since
+// their inputs are now stale; an exit with a non-nil error also
+// restarts name itself, while a nil (graceful) exit does not, since
+// the manifold may legitimately be done. A non-nil exit error is
+// additionally surfaced on the pool's fatal channel with the
+// manifold's name attached.
+func (e *manifoldEngine) supervise(ctx context.Context, name string) {
+	e.mu.Lock()
+	st := e.states[name]
+	e.mu.Unlock()
+
+	if st == nil {
+		return
+	}
+
+	waitErr := make(chan error, 1)
+
+	go func() {
+		waitErr <- st.runner.Wait()
+	}()
+
+	ticker := time.NewTicker(manifoldOutputPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Stop was requested (directly, or by a restart higher up
+			// the graph); exit is expected and already being handled
+			// there.
+			return
+
+		case err := <-waitErr:
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			if err == nil {
+				if rerr := e.restartDependents(context.Background(), name); rerr != nil {
+					e.pool.reportFatal(fmt.Errorf("restarting dependents of manifold %q: %w", name, rerr))
+				}
+
+				return
+			}
+
+			e.pool.reportFatal(fmt.Errorf("manifold %q exited: %w", name, err))
+
+			if rerr := e.restart(context.Background(), name); rerr != nil {
+				e.pool.reportFatal(fmt.Errorf("restarting manifold %q: %w", name, rerr))
+			}
+
+			return
+
+		case <-ticker.C:
+			e.mu.Lock()
+			st := e.states[name]
+			e.mu.Unlock()
+
+			if st == nil {
+				return
+			}
+
+			output := st.runner.Output()
+
+			if output == st.output {
+				continue
+			}
+
+			e.mu.Lock()
+			st.output = output
+			e.mu.Unlock()
+
+			if rerr := e.restartDependents(context.Background(), name); rerr != nil {
+				e.pool.reportFatal(fmt.Errorf("restarting dependents of manifold %q: %w", name, rerr))
+			}
+		}
+	}
+}
+
+// restart stops and restarts name and every manifold that transitively
+// depends on it, in dependency order, since all of their inputs may now
+// be stale.
+func (e *manifoldEngine) restart(ctx context.Context, name string) error {
+	return e.restartAffected(ctx, name, true)
+}
+
+// restartDependents stops and restarts every manifold that transitively
+// depends on name, in dependency order, without restarting name itself
+// — its Runner is left as it is, whether still alive (an Output
+// change) or exited gracefully and not being resurrected.
+func (e *manifoldEngine) restartDependents(ctx context.Context, name string) error {
+	return e.restartAffected(ctx, name, false)
+}
+
+// restartAffected computes which manifolds are affected by a change at
+// name — name itself when includeSelf, plus, either way, everything
+// depending on it transitively — and restarts them in dependency
+// order. It holds reconcileMu for the full topo-order-then-start
+// sequence, so concurrent calls (e.g. two supervise goroutines
+// reacting to unrelated manifolds around the same time) reconcile one
+// at a time instead of racing each other's affected-set computation
+// and startManifold calls.
+func (e *manifoldEngine) restartAffected(ctx context.Context, name string, includeSelf bool) error {
+	e.reconcileMu.Lock()
+	defer e.reconcileMu.Unlock()
+
+	e.mu.Lock()
+	order, err := e.topoOrderLocked()
+	if err != nil {
+		e.mu.Unlock()
+		return err
+	}
+
+	affected := make(map[string]bool, len(order))
+	if includeSelf {
+		affected[name] = true
+	}
+
+	for _, n := range order {
+		if n == name {
+			continue
+		}
+
+		m := e.manifolds[n]
+
+		for _, in := range m.Inputs {
+			if in == name || affected[in] {
+				affected[n] = true
+				break
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	for _, n := range order {
+		if !affected[n] {
+			continue
+		}
+
+		e.mu.Lock()
+		st := e.states[n]
+		delete(e.states, n)
+		e.mu.Unlock()
+
+		if st != nil && st.cancel != nil {
+			st.cancel()
+			st.runner.Stop()
+		}
+
+		if err := e.startManifold(ctx, n); err != nil {
+			return fmt.Errorf("manifold %q: %w", n, err)
+		}
+	}
+
+	return nil
+}
+
+// AddManifold registers a Manifold under name in the pool's dependency
+// engine. Manifolds are started (in topological order, inputs before
+// dependents) the next time Start is called.
+func (p *WorkerPool) AddManifold(name string, m Manifold) {
+	p.manifolds.add(name, m)
+}
+
+// reportFatal forwards err to the pool's fatal channel the same way a
+// worker's OnFatalError does, incrementing the same Prometheus counter.
+func (p *WorkerPool) reportFatal(err error) {
+	fatalErrorsTotal.WithLabelValues(p.systemID).Inc()
+	p.fatal <- err
+}
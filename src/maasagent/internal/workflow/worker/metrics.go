@@ -0,0 +1,110 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package worker
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	workersGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "maasagent",
+		Subsystem: "worker_pool",
+		Name:      "workers",
+		Help:      "Number of workers currently running, per group.",
+	}, []string{"system_id", "group"})
+
+	workerStartsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "maasagent",
+		Subsystem: "worker_pool",
+		Name:      "worker_starts_total",
+		Help:      "Total number of workers started, per group.",
+	}, []string{"system_id", "group"})
+
+	workerStopsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "maasagent",
+		Subsystem: "worker_pool",
+		Name:      "worker_stops_total",
+		Help:      "Total number of workers stopped, per group.",
+	}, []string{"system_id", "group"})
+
+	fatalErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "maasagent",
+		Subsystem: "worker_pool",
+		Name:      "fatal_errors_total",
+		Help:      "Total number of fatal errors reported by workers.",
+	}, []string{"system_id"})
+)
+
+// HTTPHandler returns an http.Handler exposing Prometheus metrics at
+// /metrics (worker counts and starts/stops per group, fatal-error
+// counts, plus whatever WithMetricsHandler wired into the Temporal SDK
+// itself: task-slot utilization, poll success/failure rates, and
+// workflow/activity execution durations) and a plaintext liveness
+// summary at /healthz.
+func (p *WorkerPool) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", p.healthz)
+
+	return mux
+}
+
+// healthz reports whether the main control-plane worker is running and
+// whether each registered group has at least one live worker, returning
+// 503 if either is false.
+func (p *WorkerPool) healthz(w http.ResponseWriter, _ *http.Request) {
+	p.mutex.Lock()
+	started := p.started
+	groupCounts := make(map[string]int, len(p.workers))
+
+	for group, workers := range p.workers {
+		groupCounts[group] = len(workers)
+	}
+	p.mutex.Unlock()
+
+	var body strings.Builder
+
+	healthy := started
+	fmt.Fprintf(&body, "main: %s\n", statusString(started))
+
+	for group, count := range groupCounts {
+		ok := count > 0
+		healthy = healthy && ok
+
+		fmt.Fprintf(&body, "%s: %s (%d workers)\n", group, statusString(ok), count)
+	}
+
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	w.Write([]byte(body.String())) //nolint:errcheck
+}
+
+func statusString(ok bool) string {
+	if ok {
+		return "ok"
+	}
+
+	return "down"
+}
@@ -16,6 +16,7 @@
 package worker
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -53,12 +54,24 @@ type WorkerPool struct {
 	// worker for control plane
 	main              worker.Worker
 	workerConstructor workerConstructor
-	workers           map[string][]worker.Worker
+	workers           map[string][]*trackedWorker
+	groupOrder        []string
 	workflows         map[string]interface{}
 	activities        map[string]interface{}
 	systemID          string
 	taskQueue         string
 	mutex             sync.Mutex
+
+	scheduler *Scheduler
+	schedules map[string]map[string]ScheduleSpec
+
+	autoscalers map[string]context.CancelFunc
+	hooks       map[string]groupLifecycle
+
+	manifolds *manifoldEngine
+
+	metricsHandler client.MetricsHandler
+	started        bool
 }
 
 // NewWorkerPool returns WorkerPool that has a main worker polling
@@ -70,12 +83,18 @@ func NewWorkerPool(systemID string, client client.Client,
 		systemID:          systemID,
 		taskQueue:         fmt.Sprintf("%s@main", systemID),
 		client:            client,
-		workers:           make(map[string][]worker.Worker),
+		workers:           make(map[string][]*trackedWorker),
 		workflows:         make(map[string]interface{}),
 		activities:        make(map[string]interface{}),
 		workerConstructor: defaultWorkerConstructor,
+		schedules:         make(map[string]map[string]ScheduleSpec),
+		autoscalers:       make(map[string]context.CancelFunc),
+		hooks:             make(map[string]groupLifecycle),
 	}
 
+	pool.scheduler = newScheduler(systemID, client)
+	pool.manifolds = newManifoldEngine(pool)
+
 	for _, opt := range options {
 		opt(pool)
 	}
@@ -85,8 +104,9 @@ func NewWorkerPool(systemID string, client client.Client,
 		DisableRegistrationAliasing:            true,
 		MaxConcurrentWorkflowTaskPollers:       2,
 		MaxConcurrentWorkflowTaskExecutionSize: 2,
+		MetricsHandler:                         pool.metricsHandler,
 		// Used to catch runtime errors from main
-		OnFatalError: func(err error) { pool.fatal <- err },
+		OnFatalError: pool.reportFatal,
 	})
 
 	for k, configurator := range pool.workflows {
@@ -110,9 +130,61 @@ func NewWorkerPool(systemID string, client client.Client,
 	return pool
 }
 
-// Start starts the main worker process that controls worker pool
+// Start reconciles every schedule registered via AddSchedule against
+// Temporal's Schedules API, starts every manifold registered via
+// AddManifold in dependency order, and then starts the main worker
+// process, so restarting the agent updates existing schedules in place
+// instead of duplicating them.
 func (p *WorkerPool) Start() error {
-	return p.main.Start()
+	if err := p.reconcileSchedules(context.Background()); err != nil {
+		return err
+	}
+
+	if err := p.manifolds.start(context.Background()); err != nil {
+		return err
+	}
+
+	if err := p.main.Start(); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	p.started = true
+	p.mutex.Unlock()
+
+	return nil
+}
+
+func (p *WorkerPool) reconcileSchedules(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for group, schedules := range p.schedules {
+		for name, spec := range schedules {
+			if err := p.scheduler.reconcile(ctx, p.taskQueue, group, name, spec); err != nil {
+				return fmt.Errorf("reconciling schedule %s/%s: %w", group, name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// AddSchedule registers a recurring trigger for a workflow under group,
+// analogous to an asynq periodic job, so operators can declaratively
+// wire recurring maintenance workflows (periodic rack sync, image
+// refresh, ...) instead of hand-rolling starter workflows. It is
+// reconciled against Temporal on Start; RemoveWorkers(group) tears down
+// every schedule registered under that group.
+func (p *WorkerPool) AddSchedule(group, name string, spec ScheduleSpec) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.schedules[group] == nil {
+		p.schedules[group] = make(map[string]ScheduleSpec)
+	}
+
+	p.schedules[group][name] = spec
 }
 
 func (p *WorkerPool) Error() error {
@@ -126,14 +198,21 @@ func (p *WorkerPool) Error() error {
 // Named group can be used to track workers registered for specific use cases.
 // If there is a need to remove workers, usage of a group might be handy,
 // because RemoveWorkers method is doing removal of all workers inside the group.
+// Optional WorkerLifecycleOptions register PreStop/PostStop hooks fired
+// whenever group is later torn down via DrainWorkers or Shutdown.
 func (p *WorkerPool) AddWorker(group, taskQueue string,
-	workflows, activities map[string]interface{}, opts worker.Options) error {
+	workflows, activities map[string]interface{}, opts worker.Options,
+	lifecycle ...WorkerLifecycleOption) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	opts.OnFatalError = func(err error) { p.fatal <- err }
+	opts.OnFatalError = p.reportFatal
 	opts.DisableRegistrationAliasing = true
 
+	if opts.MetricsHandler == nil {
+		opts.MetricsHandler = p.metricsHandler
+	}
+
 	w := p.workerConstructor(p.client, taskQueue, opts)
 
 	for name, fn := range workflows {
@@ -149,25 +228,37 @@ func (p *WorkerPool) AddWorker(group, taskQueue string,
 		return err
 	}
 
-	p.workers[group] = append(p.workers[group], w)
+	if _, ok := p.workers[group]; !ok {
+		p.groupOrder = append(p.groupOrder, group)
+	}
+
+	p.workers[group] = append(p.workers[group], newTrackedWorker(w, taskQueue))
+
+	hooks := p.hooks[group]
+	for _, opt := range lifecycle {
+		opt(&hooks)
+	}
+	p.hooks[group] = hooks
+
+	workerStartsTotal.WithLabelValues(p.systemID, group).Inc()
+	workersGauge.WithLabelValues(p.systemID, group).Set(float64(len(p.workers[group])))
 
 	return nil
 }
 
-// RemoveWorkers stops all the workers of a certain group and
-// removes them from the pool.
+// RemoveWorkers stops all the workers of a certain group and removes
+// them from the pool immediately, without firing any PreStop/PostStop
+// hooks or bounding the wait on a context. Use DrainWorkers for that.
 func (p *WorkerPool) RemoveWorkers(group string) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	workers, ok := p.workers[group]
-	if ok {
-		for _, w := range workers {
-			w.Stop()
-		}
-
-		delete(p.workers, group)
+	for _, tw := range p.workers[group] {
+		tw.stopTracked()
+		workerStopsTotal.WithLabelValues(p.systemID, group).Inc()
 	}
+
+	p.teardownGroupLocked(group)
 }
 
 func (p *WorkerPool) RegisterActivityWithOptions(a interface{},
@@ -196,6 +287,17 @@ func WithWorkerConstructor(fn workerConstructor) WorkerPoolOption {
 	}
 }
 
+// WithMetricsHandler sets the Temporal SDK metrics handler wired into
+// every worker.Options this pool constructs (main, AddWorker,
+// AddAutoscaledWorkers), giving task-slot utilization, poll
+// success/failure rates, and workflow/activity execution durations
+// alongside the pool's own Prometheus metrics exposed by HTTPHandler.
+func WithMetricsHandler(h client.MetricsHandler) WorkerPoolOption {
+	return func(p *WorkerPool) {
+		p.metricsHandler = h
+	}
+}
+
 // WithConfigurator adds Configurator that will be registered as a workflow
 func WithConfigurator(configurator Configurator) WorkerPoolOption {
 	return func(p *WorkerPool) {
@@ -0,0 +1,165 @@
+// Copyright (c) 2024 Canonical Ltd
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+// OverlapPolicy controls what happens when a scheduled trigger fires
+// while the previous run is still executing.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the new run entirely. This is the default.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapBufferOne buffers a single pending run to start as soon as
+	// the current one completes; further overlapping triggers are
+	// dropped.
+	OverlapBufferOne
+	// OverlapAllowAll allows runs to execute concurrently.
+	OverlapAllowAll
+)
+
+func (o OverlapPolicy) temporal() client.ScheduleOverlapPolicy {
+	switch o {
+	case OverlapBufferOne:
+		return client.ScheduleOverlapPolicyBufferOne
+	case OverlapAllowAll:
+		return client.ScheduleOverlapPolicyAllowAll
+	default:
+		return client.ScheduleOverlapPolicySkip
+	}
+}
+
+// ScheduleSpec declares a recurring trigger for a workflow, analogous to
+// a periodic job in an asynq-style scheduler. Exactly one of Cron or
+// Interval should be set; if both are, Cron takes precedence.
+type ScheduleSpec struct {
+	// Workflow is the registered workflow type started on every trigger.
+	Workflow string
+	// TaskQueue is the task queue the scheduled workflow runs on.
+	// Defaults to the pool's main task queue when empty.
+	TaskQueue string
+	// Args are passed as the scheduled workflow's input on every run.
+	Args []interface{}
+
+	// Cron is a standard cron expression, e.g. "0 */6 * * *".
+	Cron string
+	// Interval triggers the workflow on a fixed period when Cron is
+	// empty.
+	Interval time.Duration
+	// Jitter randomizes each trigger time by up to this much, to avoid
+	// a thundering herd across many agents on the same schedule.
+	Jitter time.Duration
+
+	// Overlap controls behaviour when a run is still executing at the
+	// next trigger time (default: OverlapSkip).
+	Overlap OverlapPolicy
+}
+
+func (s ScheduleSpec) temporal(defaultTaskQueue, id string) (client.ScheduleSpec, *client.ScheduleWorkflowAction) {
+	spec := client.ScheduleSpec{}
+
+	switch {
+	case s.Cron != "":
+		spec.CronExpressions = []string{s.Cron}
+	case s.Interval > 0:
+		spec.Intervals = []client.ScheduleIntervalSpec{{Every: s.Interval}}
+	}
+
+	if s.Jitter > 0 {
+		spec.Jitter = s.Jitter
+	}
+
+	taskQueue := s.TaskQueue
+	if taskQueue == "" {
+		taskQueue = defaultTaskQueue
+	}
+
+	action := &client.ScheduleWorkflowAction{
+		ID:        id,
+		Workflow:  s.Workflow,
+		TaskQueue: taskQueue,
+		Args:      s.Args,
+	}
+
+	return spec, action
+}
+
+// Scheduler manages Temporal schedules for a WorkerPool, keying each one
+// as "{systemID}/{group}/{name}" so that reconciling the same
+// (group, name) after an agent restart updates the existing schedule
+// instead of creating a duplicate.
+type Scheduler struct {
+	systemID string
+	client   client.ScheduleClient
+}
+
+func newScheduler(systemID string, c client.Client) *Scheduler {
+	return &Scheduler{
+		systemID: systemID,
+		client:   c.ScheduleClient(),
+	}
+}
+
+func (s *Scheduler) id(group, name string) string {
+	return fmt.Sprintf("%s/%s/%s", s.systemID, group, name)
+}
+
+// reconcile creates the schedule for (group, name) if it doesn't exist
+// yet, or updates it in place to match spec otherwise.
+func (s *Scheduler) reconcile(ctx context.Context, defaultTaskQueue, group, name string, spec ScheduleSpec) error {
+	id := s.id(group, name)
+	schedSpec, action := spec.temporal(defaultTaskQueue, id)
+
+	handle := s.client.GetHandle(ctx, id)
+
+	if _, err := handle.Describe(ctx); err == nil {
+		return handle.Update(ctx, client.ScheduleUpdateOptions{
+			DoUpdate: func(input client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+				input.Description.Schedule.Spec = &schedSpec
+				input.Description.Schedule.Action = action
+
+				if input.Description.Schedule.Policy == nil {
+					input.Description.Schedule.Policy = &client.SchedulePolicies{}
+				}
+
+				input.Description.Schedule.Policy.Overlap = spec.Overlap.temporal()
+
+				return &client.ScheduleUpdate{Schedule: &input.Description.Schedule}, nil
+			},
+		})
+	}
+
+	_, err := s.client.Create(ctx, client.ScheduleOptions{
+		ID:      id,
+		Spec:    schedSpec,
+		Action:  action,
+		Overlap: spec.Overlap.temporal(),
+	})
+
+	return err
+}
+
+// delete tears down the schedule registered for (group, name), if any.
+func (s *Scheduler) delete(ctx context.Context, group, name string) error {
+	return s.client.GetHandle(ctx, s.id(group, name)).Delete(ctx)
+}